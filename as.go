@@ -0,0 +1,647 @@
+package chain
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/rbranson/chain/iface"
+	"github.com/rbranson/chain/x"
+)
+
+var (
+	// ErrNotFound indicates that no value of the requested type was found in
+	// a chain.
+	ErrNotFound = errors.New("not found")
+
+	// ErrMultiple indicates that more than one value of the requested type was
+	// found in a chain where only one was expected.
+	ErrMultiple = errors.New("multiple found")
+
+	// ErrTypeMismatch indicates that a value in a chain does not match the
+	// type it was expected to have.
+	ErrTypeMismatch = errors.New("type mismatch")
+)
+
+// AsFlexible finds a value in v's chain that can be made into a T, trying
+// progressively looser strategies. Each strategy is tried against the whole
+// chain before moving on to the next, and the first success wins:
+//
+//  1. Assignability, using the same rules as As, including As methods.
+//  2. Conversion, for elements whose type is convertible to T. Integers are
+//     never converted to strings, since that yields a rune rather than the
+//     number's text.
+//  3. Dereference, for non-nil pointer elements whose pointed-to value is
+//     assignable to T.
+//  4. Text unmarshaling, for string elements when *T implements
+//     encoding.TextUnmarshaler and UnmarshalText succeeds.
+//
+// If no strategy succeeds, AsFlexible returns the zero value of T and false.
+func AsFlexible[T any](v interface{}) (T, bool) {
+	var t T
+	if As(v, &t) {
+		return t, true
+	}
+
+	tt := reflect.TypeOf(&t).Elem()
+	strategies := []func(reflect.Value) bool{
+		func(ev reflect.Value) bool {
+			if !ev.CanConvert(tt) || (isInteger(ev.Kind()) && tt.Kind() == reflect.String) {
+				return false
+			}
+			t = ev.Convert(tt).Interface().(T)
+			return true
+		},
+		func(ev reflect.Value) bool {
+			if ev.Kind() != reflect.Ptr || ev.IsNil() || !ev.Type().Elem().AssignableTo(tt) {
+				return false
+			}
+			reflect.ValueOf(&t).Elem().Set(ev.Elem())
+			return true
+		},
+		func(ev reflect.Value) bool {
+			u, ok := interface{}(&t).(encoding.TextUnmarshaler)
+			if !ok || ev.Kind() != reflect.String {
+				return false
+			}
+			if err := u.UnmarshalText([]byte(ev.String())); err != nil {
+				t = *new(T)
+				return false
+			}
+			return true
+		},
+	}
+
+	for _, try := range strategies {
+		found := false
+		walk(v, func(e interface{}) bool {
+			if e == nil {
+				return true
+			}
+			found = try(reflect.ValueOf(e))
+			return !found
+		})
+		if found {
+			return t, true
+		}
+	}
+
+	return t, false
+}
+
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// AsAllValues returns every value in v's chain that is assignable to the type
+// pointed to by example, which is typically of the form (*T)(nil). Nil values
+// are skipped.
+//
+// The returned reflect.Values hold the values' concrete types. Callers can
+// assemble them into a slice of the example's type with reflect.Append:
+//
+//	t := reflect.TypeOf(example).Elem()
+//	s := reflect.Append(reflect.MakeSlice(reflect.SliceOf(t), 0, len(vals)), vals...)
+//
+// AsAllValues panics if example is not a pointer.
+func AsAllValues(v interface{}, example interface{}) []reflect.Value {
+	ex, err := x.MakeTypeExample(example)
+	if err != nil {
+		panic("chain: example " + err.Error())
+	}
+
+	var vals []reflect.Value
+	walk(v, func(e interface{}) bool {
+		if e != nil && ex.AssignableFrom(reflect.TypeOf(e)) {
+			vals = append(vals, reflect.ValueOf(e))
+		}
+		return true
+	})
+	return vals
+}
+
+// AsAll appends every value in v's chain that is assignable to the element
+// type of the slice pointed to by target to that slice, from outermost to
+// innermost, and reports whether any were appended. Nil values are skipped.
+//
+// AsAll panics if target is not a non-nil pointer to a slice.
+func AsAll(v interface{}, target interface{}) bool {
+	rv, ok := x.ValueOf(target)
+	if !ok || rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		panic("chain: target must be a non-nil pointer to a slice")
+	}
+
+	s := rv.Elem()
+	vals := AsAllValues(v, reflect.New(s.Type().Elem()).Interface())
+	if len(vals) == 0 {
+		return false
+	}
+	s.Set(reflect.Append(s, vals...))
+	return true
+}
+
+// Scan is like calling As with each of targets in turn, but walks v's chain
+// only once. Each target is set to the first value in the chain that matches
+// it, and the walk stops once every target has been set. Scan returns the
+// number of targets that were set; the others are left unchanged.
+//
+// Scan panics if any target is not a non-nil pointer.
+func Scan(v interface{}, targets ...interface{}) int {
+	s := &scanner{
+		targets: targets,
+		vals:    make([]reflect.Value, len(targets)),
+		exs:     make([]x.TypeExample, len(targets)),
+		set:     make([]bool, len(targets)),
+	}
+	for i, t := range targets {
+		s.vals[i], s.exs[i] = asTarget(t)
+	}
+	if len(targets) > 0 {
+		s.scan(v)
+	}
+	return s.n
+}
+
+type scanner struct {
+	targets []interface{}
+	vals    []reflect.Value
+	exs     []x.TypeExample
+	set     []bool
+	n       int
+}
+
+// scan walks v's chain as As does, setting the targets not yet set, and
+// reports whether every target has been set.
+func (s *scanner) scan(v interface{}) bool {
+	for {
+		for i, t := range s.targets {
+			if !s.set[i] && asOne(v, t, s.vals[i], s.exs[i]) {
+				s.set[i] = true
+				if s.n++; s.n == len(s.targets) {
+					return true
+				}
+			}
+		}
+
+		for _, b := range branches(elem(v)) {
+			if s.scan(b) {
+				return true
+			}
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return false
+		}
+	}
+}
+
+// Destructure sets the values pointed to by ptrs to the successive elements
+// of v's chain, as described by Walk, so that the first is set to v itself,
+// the second to the value it wraps, and so on. Each element must match its
+// pointer using the rules described by As, although a nil element matches
+// any pointer to a nillable type, which is set to nil. Elements beyond the
+// last pointer are ignored.
+//
+// If an element does not match, Destructure returns an error wrapping
+// ErrTypeMismatch, and if the chain has fewer elements than there are
+// pointers, an error wrapping ErrNotFound. In either case, the pointers
+// before the one that failed have already been set.
+//
+// Destructure panics if any of ptrs is not a non-nil pointer.
+func Destructure(v interface{}, ptrs ...interface{}) error {
+	vals := make([]reflect.Value, len(ptrs))
+	exs := make([]x.TypeExample, len(ptrs))
+	for i, p := range ptrs {
+		vals[i], exs[i] = asTarget(p)
+	}
+
+	i := 0
+	var err error
+	walk(v, func(e interface{}) bool {
+		if i == len(ptrs) {
+			return false
+		}
+
+		switch {
+		case e == nil && allowed(exs[i:i+1], nil):
+			vals[i].Elem().Set(reflect.Zero(exs[i].Type()))
+		case e == nil || !asOne(e, ptrs[i], vals[i], exs[i]):
+			err = fmt.Errorf("chain: element %d of type %T: %w", i, e, ErrTypeMismatch)
+			return false
+		}
+		i++
+		return true
+	})
+	if err == nil && i < len(ptrs) {
+		err = fmt.Errorf("chain: element %d: %w", i, ErrNotFound)
+	}
+	return err
+}
+
+// AsDir is like As, but finds either the outermost or the innermost value in
+// v's chain that matches target, depending on fromInnermost.
+//
+// When fromInnermost is false, AsDir is equivalent to As and stops at the
+// first match. When fromInnermost is true, the entire chain must be walked to
+// find the innermost match, which costs time proportional to the length of
+// the chain even if an outer value matches. If the chain branches, the
+// innermost match is the last one in the order in which As searches.
+//
+// AsDir panics if target is not a non-nil pointer.
+func AsDir(v interface{}, target interface{}, fromInnermost bool) bool {
+	if !fromInnermost {
+		return As(v, target)
+	}
+
+	targetVal, targetEx := asTarget(target)
+	return asLast(v, target, targetVal, targetEx)
+}
+
+// asLast sets target to the last value in v's chain that matches it, in the
+// order in which As searches, and reports whether there was one.
+func asLast(v interface{}, target interface{}, targetVal reflect.Value, targetEx x.TypeExample) bool {
+	found := false
+	for {
+		if asOne(v, target, targetVal, targetEx) {
+			found = true
+		}
+
+		for _, b := range branches(elem(v)) {
+			if asLast(b, target, targetVal, targetEx) {
+				found = true
+			}
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return found
+		}
+	}
+}
+
+// AsFromHere returns the leading run of values in v's chain that are of type
+// T, starting with v itself and stopping at the first value that is not a T.
+// Any values of type T beyond that point are not included. If v itself is not
+// a T, the result is empty.
+func AsFromHere[T any](v interface{}) []T {
+	var ts []T
+	walk(v, func(e interface{}) bool {
+		t, ok := e.(T)
+		if ok {
+			ts = append(ts, t)
+		}
+		return ok
+	})
+	return ts
+}
+
+// AsTagged fills the exported fields of the struct pointed to by structTarget
+// that have a tagKey struct tag, returning the number of fields filled.
+//
+// Each tagged field is set to the first value in v's chain whose type name
+// equals the tag's value and which is assignable to the field. A type name
+// matches either in its package-qualified form, such as "time.Duration", or
+// in its unqualified form, such as "Duration". Fields with no matching value
+// are left unchanged.
+//
+// AsTagged panics if structTarget is not a non-nil pointer to a struct.
+func AsTagged(v interface{}, structTarget interface{}, tagKey string) int {
+	rv, ok := x.ValueOf(structTarget)
+	if !ok || rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic("chain: structTarget must be a non-nil pointer to a struct")
+	}
+
+	sv := rv.Elem()
+	elems := collect(v)
+	filled := 0
+	for i := 0; i < sv.NumField(); i++ {
+		f := sv.Type().Field(i)
+		name, ok := f.Tag.Lookup(tagKey)
+		if !ok || !f.IsExported() {
+			continue
+		}
+
+		for _, e := range elems {
+			if e == nil {
+				continue
+			}
+
+			et := reflect.TypeOf(e)
+			if (et.String() == name || et.Name() == name) && et.AssignableTo(f.Type) {
+				sv.Field(i).Set(reflect.ValueOf(e))
+				filled++
+				break
+			}
+		}
+	}
+	return filled
+}
+
+// AsAny2 finds the first value in v's chain that matches either an A or a B,
+// using the rules described by As. If the first match is an A, it is returned
+// as a with which set to 0. If it is a B, it is returned as b with which set
+// to 1. The other result is left as its zero value. If neither is found,
+// which is -1.
+//
+// If a value matches both A and B, A takes precedence. Branches are searched
+// as As does.
+func AsAny2[A, B any](v interface{}) (a A, b B, which int) {
+	aVal, aEx := asTarget(&a)
+	bVal, bEx := asTarget(&b)
+
+	var search func(v interface{}) int
+	search = func(v interface{}) int {
+		for {
+			if asOne(v, &a, aVal, aEx) {
+				return 0
+			}
+
+			if asOne(v, &b, bVal, bEx) {
+				return 1
+			}
+
+			for _, br := range branches(elem(v)) {
+				if which := search(br); which >= 0 {
+					return which
+				}
+			}
+
+			var ok bool
+			v, ok = Unwrap(v)
+			if !ok {
+				return -1
+			}
+		}
+	}
+
+	which = search(v)
+	return a, b, which
+}
+
+// AsExactlyOne returns the only value of type T in v's chain. If there is no
+// such value, the returned error wraps ErrNotFound, and if there is more than
+// one, it wraps ErrMultiple, so that either can be detected with errors.Is.
+//
+// The chain is walked to its end, unless a second value of type T is found.
+func AsExactlyOne[T any](v interface{}) (T, error) {
+	var t T
+	n := 0
+	walk(v, func(e interface{}) bool {
+		if et, ok := e.(T); ok {
+			t = et
+			n++
+		}
+		return n < 2
+	})
+
+	switch n {
+	case 0:
+		return t, fmt.Errorf("chain: %v: %w", reflect.TypeOf(&t).Elem(), ErrNotFound)
+	case 1:
+		return t, nil
+	default:
+		return *new(T), fmt.Errorf("chain: %v: %w", reflect.TypeOf(&t).Elem(), ErrMultiple)
+	}
+}
+
+// AsLazy returns a function that finds the first value in v's chain that
+// matches a T, using the rules described by As. The chain is not walked until
+// the function is first called, and the result is cached for subsequent
+// calls, which may be made from multiple goroutines.
+func AsLazy[T any](v interface{}) func() (T, bool) {
+	var (
+		once sync.Once
+		t    T
+		ok   bool
+	)
+	return func() (T, bool) {
+		once.Do(func() {
+			ok = As(v, &t)
+		})
+		return t, ok
+	}
+}
+
+// AsBest returns the greatest value of type T in v's chain according to less,
+// walking the entire chain. If several values are equally great, the
+// outermost is returned. If there is no value of type T, AsBest returns the
+// zero value of T and false.
+func AsBest[T any](v interface{}, less func(a, b T) bool) (T, bool) {
+	var best T
+	found := false
+	walk(v, func(e interface{}) bool {
+		if t, ok := e.(T); ok && (!found || less(best, t)) {
+			best, found = t, true
+		}
+		return true
+	})
+	return best, found
+}
+
+// AsIntoSyncMap stores each value in v's chain into m, keyed by its
+// reflect.Type. Only the first (outermost) value of each type is stored, and
+// later values of the same type are ignored, as are types that already have an
+// entry in m. Nil values are skipped.
+func AsIntoSyncMap(v interface{}, m *sync.Map) {
+	walk(v, func(e interface{}) bool {
+		if e != nil {
+			m.LoadOrStore(reflect.TypeOf(e), e)
+		}
+		return true
+	})
+}
+
+// AsByInterface groups the values in v's chain by the interfaces they
+// implement. Each of ifaceExamples must be a pointer to an interface type,
+// typically of the form (*I)(nil). The result maps each interface type to the
+// values in the chain that implement it, as reported by
+// reflect.Type.Implements, in chain order. A value appears under every
+// interface it implements. Interfaces that no value implements have no entry.
+//
+// AsByInterface panics if an example is not a pointer to an interface type.
+func AsByInterface(v interface{}, ifaceExamples ...interface{}) map[reflect.Type][]interface{} {
+	ifaces := make([]reflect.Type, len(ifaceExamples))
+	for i, example := range ifaceExamples {
+		ex, err := x.MakeTypeExample(example)
+		if err != nil || ex.Type().Kind() != reflect.Interface {
+			panic("chain: example must be a pointer to an interface type")
+		}
+		ifaces[i] = ex.Type()
+	}
+
+	groups := make(map[reflect.Type][]interface{})
+	walk(v, func(e interface{}) bool {
+		if e == nil {
+			return true
+		}
+		for _, it := range ifaces {
+			if reflect.TypeOf(e).Implements(it) {
+				groups[it] = append(groups[it], e)
+			}
+		}
+		return true
+	})
+	return groups
+}
+
+// AsCtx is like As, but stops walking v's chain if ctx is done, returning
+// false and the context's error. The context is checked before each value in
+// the chain is examined, so an expensive As method is not interrupted, but no
+// further values are examined once it returns.
+//
+// Branches are searched as As does.
+//
+// AsCtx panics if target is not a non-nil pointer.
+func AsCtx(ctx context.Context, v, target interface{}) (bool, error) {
+	targetVal, targetEx := asTarget(target)
+	return asCtx(ctx, v, target, targetVal, targetEx)
+}
+
+// asCtx implements AsCtx for a validated target.
+func asCtx(ctx context.Context, v interface{}, target interface{}, targetVal reflect.Value, targetEx x.TypeExample) (bool, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		if asOne(v, target, targetVal, targetEx) {
+			return true, nil
+		}
+
+		for _, b := range branches(elem(v)) {
+			if found, err := asCtx(ctx, b, target, targetVal, targetEx); found || err != nil {
+				return found, err
+			}
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return false, nil
+		}
+	}
+}
+
+// AsCombine builds a T from the values in v's chain by folding them into init
+// with combine, from outermost to innermost, and returns the result. combine
+// is called for every value and may return acc unchanged for values it does
+// not use. Since outer values are combined first, a combine function that
+// keeps existing fields gives outer values precedence over inner ones.
+func AsCombine[T any](v interface{}, combine func(acc T, cur interface{}) T, init T) T {
+	acc := init
+	walk(v, func(e interface{}) bool {
+		acc = combine(acc, e)
+		return true
+	})
+	return acc
+}
+
+// AsArray fills dst with the values of type T in v's chain, from outermost to
+// innermost, and returns the number filled. Since type parameters cannot
+// abstract over array lengths, dst is typically a slice of a caller's
+// fixed-size array, which lets the results stay on the stack:
+//
+//	var arr [4]string
+//	n := chain.AsArray(v, arr[:])
+//
+// If there are more than len(dst) values of type T, the extras are ignored
+// and the walk stops early. If there are fewer, the remaining entries of dst
+// are set to the zero value of T.
+func AsArray[T any](v interface{}, dst []T) int {
+	n := 0
+	if len(dst) > 0 {
+		walk(v, func(e interface{}) bool {
+			if t, ok := e.(T); ok {
+				dst[n] = t
+				n++
+			}
+			return n < len(dst)
+		})
+	}
+
+	var zero T
+	for i := n; i < len(dst); i++ {
+		dst[i] = zero
+	}
+	return n
+}
+
+// AsIntoMap stores each value in v's chain into m, keyed by the string form of
+// its type as returned by reflect.Type.String, such as "string" or
+// "*time.Location". Only the first (outermost) value of each type is stored,
+// and later values of the same type are ignored, as are types that already
+// have an entry in m. Nil values are skipped.
+//
+// AsIntoMap panics if m is nil, since it cannot be allocated on the caller's
+// behalf.
+func AsIntoMap(v interface{}, m map[string]interface{}) {
+	if m == nil {
+		panic("chain: m must not be nil")
+	}
+
+	walk(v, func(e interface{}) bool {
+		if e == nil {
+			return true
+		}
+		k := reflect.TypeOf(e).String()
+		if _, ok := m[k]; !ok {
+			m[k] = e
+		}
+		return true
+	})
+}
+
+// AsFor finds the first value in v's chain that is a T, or that has a method
+// As(interface{}) bool such that As(*T) returns true, and returns it. If there
+// is no such value, AsFor returns the zero value of T and false.
+//
+// AsFor is a type-safe alternative to As: since it has no target parameter,
+// it cannot panic, and it uses a type assertion rather than reflection to
+// check each value, which makes it considerably faster.
+func AsFor[T any](v interface{}) (T, bool) {
+	var t T
+	found := false
+	walk(v, func(e interface{}) bool {
+		if et, ok := e.(T); ok {
+			t, found = et, true
+		} else if asv, ok := e.(iface.As); ok {
+			found = asv.As(&t)
+		}
+		return !found
+	})
+	return t, found
+}
+
+// MustAs is like AsFor, but panics if no value in v's chain matches a T. The
+// panic value is an error wrapping ErrNotFound that names T.
+func MustAs[T any](v interface{}) T {
+	t, ok := AsFor[T](v)
+	if !ok {
+		panic(fmt.Errorf("chain: %v: %w", reflect.TypeOf(&t).Elem(), ErrNotFound))
+	}
+	return t
+}
+
+// OfType returns every value in v's chain that is a T, from outermost to
+// innermost.
+func OfType[T any](v interface{}) []T {
+	var ts []T
+	walk(v, func(e interface{}) bool {
+		if t, ok := e.(T); ok {
+			ts = append(ts, t)
+		}
+		return true
+	})
+	return ts
+}
@@ -0,0 +1,534 @@
+package chain_test
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+type celsius float64
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+func TestAsFlexible(t *testing.T) {
+	// assignable
+	s, ok := chain.AsFlexible[string](chain.Build(1, "a"))
+	assert.True(t, ok)
+	assert.Equals(t, "a", s)
+
+	// assignable wins over convertible, even deeper in the chain
+	f, ok := chain.AsFlexible[float64](chain.Build(2.5, celsius(1)))
+	assert.True(t, ok)
+	assert.Equals(t, 2.5, f)
+
+	// convertible
+	c, ok := chain.AsFlexible[celsius](chain.Build("x", 21.5))
+	assert.True(t, ok)
+	assert.Equals(t, celsius(21.5), c)
+
+	// integers are not converted to strings
+	_, ok = chain.AsFlexible[string](65)
+	assert.False(t, ok)
+
+	// dereference
+	n := 42
+	i, ok := chain.AsFlexible[int](chain.Build(&n, "x"))
+	assert.True(t, ok)
+	assert.Equals(t, 42, i)
+
+	var np *int
+	_, ok = chain.AsFlexible[int](np)
+	assert.False(t, ok)
+
+	// text unmarshaling, skipping strings that fail to unmarshal
+	l, ok := chain.AsFlexible[level](chain.Build("high", "bogus"))
+	assert.True(t, ok)
+	assert.Equals(t, level(2), l)
+
+	l, ok = chain.AsFlexible[level]("bogus")
+	assert.False(t, ok)
+	assert.Equals(t, level(0), l)
+
+	// no match
+	_, ok = chain.AsFlexible[struct{ A int }](chain.Build(1, "a"))
+	assert.False(t, ok)
+}
+
+func TestAsAllValues(t *testing.T) {
+	assert.Panics(t, "chain: example must be a pointer", func() {
+		chain.AsAllValues("a", "")
+	})
+
+	vals := chain.AsAllValues(chain.Build("a", 1, "b", 2.5), (*string)(nil))
+	assert.Equals(t, 2, len(vals))
+	assert.Equals(t, "b", vals[0].Interface())
+	assert.Equals(t, "a", vals[1].Interface())
+
+	st := reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	vals = chain.AsAllValues(chain.Build(time.Second, 1, time.Minute), (*fmt.Stringer)(nil))
+	ss := reflect.Append(reflect.MakeSlice(reflect.SliceOf(st), 0, len(vals)), vals...)
+	assert.Equals(t, []fmt.Stringer{time.Minute, time.Second}, ss.Interface())
+
+	vals = chain.AsAllValues(chain.Build(1, 2, 3), (*int)(nil))
+	ints := reflect.Append(reflect.MakeSlice(reflect.TypeOf([]int(nil)), 0, len(vals)), vals...)
+	assert.Equals(t, []int{3, 2, 1}, ints.Interface())
+}
+
+func TestAsAll(t *testing.T) {
+	assert.Panics(t, "chain: target must be a non-nil pointer to a slice", func() {
+		chain.AsAll("a", new(string))
+	})
+	assert.Panics(t, "chain: target must be a non-nil pointer to a slice", func() {
+		chain.AsAll("a", (*[]string)(nil))
+	})
+
+	strs := []string{"x"}
+	assert.True(t, chain.AsAll(chain.Build("a", 1, nil, "b"), &strs))
+	assert.Equals(t, []string{"x", "b", "a"}, strs)
+
+	var ss []fmt.Stringer
+	assert.True(t, chain.AsAll(chain.Build(time.Second, 1, time.Minute), &ss))
+	assert.Equals(t, []fmt.Stringer{time.Minute, time.Second}, ss)
+
+	var fs []float64
+	assert.False(t, chain.AsAll(chain.Build(1, 2), &fs))
+	assert.Equals(t, []float64(nil), fs)
+}
+
+func TestScan(t *testing.T) {
+	assert.Panics(t, "chain: target must be a pointer", func() {
+		chain.Scan("a", "")
+	})
+
+	var (
+		s  string
+		i  int
+		st fmt.Stringer
+		f  = 1.5
+	)
+	ch := chain.Build("a", time.Second, 1, "b", 2)
+	assert.Equals(t, 3, chain.Scan(ch, &s, &i, &st, &f))
+	assert.Equals(t, "b", s)
+	assert.Equals(t, 2, i)
+	assert.Equals(t, time.Second, st)
+	assert.Equals(t, 1.5, f)
+
+	assert.Equals(t, 2, chain.Scan(chain.Join("x", chain.Build(7, "y")), &s, &i))
+	assert.Equals(t, "x", s)
+	assert.Equals(t, 7, i)
+
+	assert.Equals(t, 0, chain.Scan(ch))
+}
+
+func TestDestructure(t *testing.T) {
+	var (
+		s   string
+		i   int
+		st  fmt.Stringer
+		err error
+	)
+	ch := chain.Build(time.Second, 1, "b")
+	assert.Ok(t, chain.Destructure(ch, &s, &i, &st))
+	assert.Equals(t, "b", s)
+	assert.Equals(t, 1, i)
+	assert.Equals(t, time.Second, st)
+
+	assert.Ok(t, chain.Destructure(ch, &s))
+	assert.Ok(t, chain.Destructure(chain.Build(nil, "c"), &s, &err))
+	assert.Equals(t, "c", s)
+	assert.Equals(t, nil, err)
+
+	err = chain.Destructure(ch, &s, &s)
+	assert.True(t, errors.Is(err, chain.ErrTypeMismatch))
+	assert.Equals(t, "chain: element 1 of type int: type mismatch", err.Error())
+
+	err = chain.Destructure(chain.Build(nil, "c"), &s, &i)
+	assert.True(t, errors.Is(err, chain.ErrTypeMismatch))
+
+	err = chain.Destructure("a", &s, &i)
+	assert.True(t, errors.Is(err, chain.ErrNotFound))
+	assert.Equals(t, "chain: element 1: not found", err.Error())
+
+	assert.Panics(t, "chain: target must be a pointer", func() {
+		chain.Destructure(ch, &s, "")
+	})
+}
+
+func TestAsBranches(t *testing.T) {
+	j := chain.Join("x", chain.Build("y", 1))
+
+	var s string
+	assert.True(t, chain.AsDir(j, &s, true))
+	assert.Equals(t, "y", s)
+	assert.True(t, chain.AsDir(j, &s, false))
+	assert.Equals(t, "x", s)
+
+	a, b, which := chain.AsAny2[int, string](j)
+	assert.Equals(t, 1, which)
+	assert.Equals(t, 0, a)
+	assert.Equals(t, "x", b)
+
+	_, _, which = chain.AsAny2[float64, bool](j)
+	assert.Equals(t, -1, which)
+
+	var i int
+	found, err := chain.AsCtx(context.Background(), j, &i)
+	assert.Ok(t, err)
+	assert.True(t, found)
+	assert.Equals(t, 1, i)
+}
+
+func TestAsDir(t *testing.T) {
+	assert.Panics(t, "chain: target must be a pointer", func() {
+		chain.AsDir(nil, "", true)
+	})
+
+	ch := chain.Build("a", 1, "b", 2)
+
+	var s string
+	assert.True(t, chain.AsDir(ch, &s, false))
+	assert.Equals(t, "b", s)
+	assert.True(t, chain.AsDir(ch, &s, true))
+	assert.Equals(t, "a", s)
+
+	var i int
+	assert.True(t, chain.AsDir(ch, &i, true))
+	assert.Equals(t, 1, i)
+
+	var f float64
+	assert.False(t, chain.AsDir(ch, &f, true))
+	assert.False(t, chain.AsDir(ch, &f, false))
+}
+
+func TestAsFromHere(t *testing.T) {
+	ch := chain.Build("z", 1, "a", "b", "c")
+	assert.Equals(t, []string{"c", "b", "a"}, chain.AsFromHere[string](ch))
+	assert.Equals(t, 0, len(chain.AsFromHere[int](ch)))
+	assert.Equals(t, []interface{}{"c", "b", "a", 1, "z"}, chain.AsFromHere[interface{}](ch))
+}
+
+func TestAsTagged(t *testing.T) {
+	assert.Panics(t, "chain: structTarget must be a non-nil pointer to a struct", func() {
+		chain.AsTagged("a", struct{}{}, "chain")
+	})
+
+	var dst struct {
+		Timeout  time.Duration `chain:"time.Duration"`
+		Name     string        `chain:"string"`
+		Level    level         `chain:"level"`
+		Missing  int           `chain:"int"`
+		Untagged string
+		hidden   string `chain:"string"`
+	}
+
+	ch := chain.Build(level(2), "inner", time.Second, "outer")
+	assert.Equals(t, 3, chain.AsTagged(ch, &dst, "chain"))
+	assert.Equals(t, time.Second, dst.Timeout)
+	assert.Equals(t, "outer", dst.Name)
+	assert.Equals(t, level(2), dst.Level)
+	assert.Equals(t, 0, dst.Missing)
+	assert.Equals(t, "", dst.Untagged)
+	assert.Equals(t, "", dst.hidden)
+}
+
+func TestAsAny2(t *testing.T) {
+	ch := chain.Build(1, "a", 2.5)
+
+	s, f, which := chain.AsAny2[string, float64](ch)
+	assert.Equals(t, 1, which)
+	assert.Equals(t, "", s)
+	assert.Equals(t, 2.5, f)
+
+	s, i, which := chain.AsAny2[string, int](ch)
+	assert.Equals(t, 0, which)
+	assert.Equals(t, "a", s)
+	assert.Equals(t, 0, i)
+
+	_, _, which = chain.AsAny2[level, celsius](ch)
+	assert.Equals(t, -1, which)
+
+	// A wins when both match
+	_, s, which = chain.AsAny2[interface{}, string](ch)
+	assert.Equals(t, 0, which)
+	assert.Equals(t, "", s)
+}
+
+func TestAsExactlyOne(t *testing.T) {
+	ch := chain.Build("a", 1, "b")
+
+	i, err := chain.AsExactlyOne[int](ch)
+	assert.Ok(t, err)
+	assert.Equals(t, 1, i)
+
+	s, err := chain.AsExactlyOne[string](ch)
+	assert.True(t, errors.Is(err, chain.ErrMultiple))
+	assert.Equals(t, "chain: string: multiple found", err.Error())
+	assert.Equals(t, "", s)
+
+	_, err = chain.AsExactlyOne[float64](ch)
+	assert.True(t, errors.Is(err, chain.ErrNotFound))
+	assert.Equals(t, "chain: float64: not found", err.Error())
+}
+
+type countingUnwrapper struct {
+	wrapped chain.Holder
+	calls   *int
+}
+
+func (c *countingUnwrapper) Unwrap() (interface{}, bool) {
+	*c.calls++
+	return c.wrapped.Get()
+}
+
+func TestAsLazy(t *testing.T) {
+	calls := 0
+	ch := &countingUnwrapper{wrapped: chain.Hold("a"), calls: &calls}
+
+	get := chain.AsLazy[string](ch)
+	assert.Equals(t, 0, calls)
+
+	s, ok := get()
+	assert.True(t, ok)
+	assert.Equals(t, "a", s)
+	assert.Equals(t, 1, calls)
+
+	s, ok = get()
+	assert.True(t, ok)
+	assert.Equals(t, "a", s)
+	assert.Equals(t, 1, calls)
+
+	_, ok = chain.AsLazy[int](ch)()
+	assert.False(t, ok)
+}
+
+func TestAsBest(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+
+	i, ok := chain.AsBest(chain.Build(3, "x", 7, 5), less)
+	assert.True(t, ok)
+	assert.Equals(t, 7, i)
+
+	_, ok = chain.AsBest(chain.Build("x", "y"), less)
+	assert.False(t, ok)
+
+	byLen := func(a, b string) bool { return len(a) < len(b) }
+	s, ok := chain.AsBest(chain.Build("ab", "cd", "e"), byLen)
+	assert.True(t, ok)
+	assert.Equals(t, "cd", s)
+}
+
+func TestAsIntoSyncMap(t *testing.T) {
+	var m sync.Map
+	m.Store(reflect.TypeOf(2.5), 1.5)
+	chain.AsIntoSyncMap(chain.Build("a", 1, nil, 2.5, "b"), &m)
+
+	got := map[reflect.Type]interface{}{}
+	m.Range(func(k, v interface{}) bool {
+		got[k.(reflect.Type)] = v
+		return true
+	})
+	assert.Equals(t, map[reflect.Type]interface{}{
+		reflect.TypeOf(""):  "b",
+		reflect.TypeOf(0):   1,
+		reflect.TypeOf(0.0): 1.5,
+	}, got)
+}
+
+func TestAsByInterface(t *testing.T) {
+	assert.Panics(t, "chain: example must be a pointer to an interface type", func() {
+		chain.AsByInterface("a", (*string)(nil))
+	})
+
+	lv := level(1)
+	errA := errors.New("a")
+	ch := chain.Build(time.Second, &lv, errA, "x")
+
+	stringer := reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	errType := reflect.TypeOf((*error)(nil)).Elem()
+	unmarshaler := reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	writer := reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+	groups := chain.AsByInterface(ch,
+		(*fmt.Stringer)(nil),
+		(*error)(nil),
+		(*encoding.TextUnmarshaler)(nil),
+		(*io.Writer)(nil),
+	)
+	assert.Equals(t, []interface{}{time.Second}, groups[stringer])
+	assert.Equals(t, []interface{}{errA}, groups[errType])
+	assert.Equals(t, []interface{}{&lv}, groups[unmarshaler])
+	_, ok := groups[writer]
+	assert.False(t, ok)
+}
+
+type cancelingAs struct {
+	cancel func()
+}
+
+func (c *cancelingAs) As(target interface{}) bool {
+	c.cancel()
+	return false
+}
+
+func TestAsCtx(t *testing.T) {
+	ctx := context.Background()
+	ch := chain.Build("a", 1)
+
+	var s string
+	ok, err := chain.AsCtx(ctx, ch, &s)
+	assert.Ok(t, err)
+	assert.True(t, ok)
+	assert.Equals(t, "a", s)
+
+	var f float64
+	ok, err = chain.AsCtx(ctx, ch, &f)
+	assert.Ok(t, err)
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithCancel(ctx)
+	ch = chain.Build("b", &cancelingAs{cancel: cancel})
+	s = ""
+	ok, err = chain.AsCtx(ctx, ch, &s)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.False(t, ok)
+	assert.Equals(t, "", s)
+}
+
+func TestAsCombine(t *testing.T) {
+	type config struct {
+		Host string
+		Port int
+	}
+
+	ch := chain.Build(config{Host: "inner", Port: 80}, "ignored", config{Host: "outer"})
+	cfg := chain.AsCombine(ch, func(acc config, cur interface{}) config {
+		c, ok := cur.(config)
+		if !ok {
+			return acc
+		}
+		if acc.Host == "" {
+			acc.Host = c.Host
+		}
+		if acc.Port == 0 {
+			acc.Port = c.Port
+		}
+		return acc
+	}, config{})
+	assert.Equals(t, config{Host: "outer", Port: 80}, cfg)
+
+	n := chain.AsCombine(ch, func(acc int, cur interface{}) int { return acc + 1 }, 10)
+	assert.Equals(t, 13, n)
+}
+
+func TestAsArray(t *testing.T) {
+	ch := chain.Build("a", 1, "b", "c")
+
+	var two [2]string
+	assert.Equals(t, 2, chain.AsArray(ch, two[:]))
+	assert.Equals(t, [2]string{"c", "b"}, two)
+
+	four := [4]string{"w", "x", "y", "z"}
+	assert.Equals(t, 3, chain.AsArray(ch, four[:]))
+	assert.Equals(t, [4]string{"c", "b", "a", ""}, four)
+
+	var none [0]string
+	assert.Equals(t, 0, chain.AsArray(ch, none[:]))
+}
+
+func TestAsIntoMap(t *testing.T) {
+	assert.Panics(t, "chain: m must not be nil", func() {
+		chain.AsIntoMap("a", nil)
+	})
+
+	m := map[string]interface{}{"float64": 1.5}
+	chain.AsIntoMap(chain.Build("a", 1, nil, 2.5, time.UTC, "b"), m)
+	assert.Equals(t, map[string]interface{}{
+		"string":         "b",
+		"int":            1,
+		"float64":        1.5,
+		"*time.Location": time.UTC,
+	}, m)
+}
+
+func TestAsFor(t *testing.T) {
+	ch := chain.Build("a", 1, "b")
+
+	s, ok := chain.AsFor[string](ch)
+	assert.True(t, ok)
+	assert.Equals(t, "b", s)
+
+	i, ok := chain.AsFor[int](ch)
+	assert.True(t, ok)
+	assert.Equals(t, 1, i)
+
+	_, ok = chain.AsFor[float64](ch)
+	assert.False(t, ok)
+
+	st, ok := chain.AsFor[fmt.Stringer](chain.Build(time.Second, "x"))
+	assert.True(t, ok)
+	assert.Equals(t, time.Second, st)
+
+	// As methods are consulted
+	hs := "hello"
+	p, ok := chain.AsFor[*string](&unwrappable{wrapped: chain.Hold(&asMatcher{to: new(*string)})})
+	assert.True(t, ok)
+	assert.Equals(t, (*string)(nil), p)
+	_, ok = chain.AsFor[*string](&asMatcher{to: &hs})
+	assert.False(t, ok)
+}
+
+func BenchmarkAs(b *testing.B) {
+	ch := chain.Build(1, "a", "b", "c")
+	for i := 0; i < b.N; i++ {
+		var n int
+		chain.As(ch, &n)
+	}
+}
+
+func BenchmarkAsFor(b *testing.B) {
+	ch := chain.Build(1, "a", "b", "c")
+	for i := 0; i < b.N; i++ {
+		chain.AsFor[int](ch)
+	}
+}
+
+func TestMustAs(t *testing.T) {
+	assert.Equals(t, 1, chain.MustAs[int](chain.Build(1, "a")))
+
+	defer func() {
+		err, ok := recover().(error)
+		assert.True(t, ok)
+		assert.True(t, errors.Is(err, chain.ErrNotFound))
+		assert.Equals(t, "chain: float64: not found", err.Error())
+	}()
+	chain.MustAs[float64](chain.Build(1, "a"))
+}
+
+func TestOfType(t *testing.T) {
+	ch := chain.Build("a", 1, "b", time.Second, 2)
+	assert.Equals(t, []string{"b", "a"}, chain.OfType[string](ch))
+	assert.Equals(t, []int{2, 1}, chain.OfType[int](ch))
+	assert.Equals(t, []fmt.Stringer{time.Second}, chain.OfType[fmt.Stringer](ch))
+	assert.Equals(t, 0, len(chain.OfType[float64](ch)))
+}
@@ -0,0 +1,558 @@
+package chain
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/rbranson/chain/iface"
+	"github.com/rbranson/chain/x"
+)
+
+var (
+	// ErrTypeNotAllowed indicates that a value passed to TryBuild is not
+	// assignable to any of the types in BuildOptions.AllowedTypes.
+	ErrTypeNotAllowed = errors.New("type not allowed")
+
+	// ErrWrapRefused indicates that a value passed to TryBuild implements
+	// Wrap, but its Wrap method returned false, when BuildOptions.Strict is
+	// set, or that a wrapper created by BuildOptions.Wrapper returned false.
+	ErrWrapRefused = errors.New("wrap refused")
+
+	// ErrTooLong indicates that more values were passed to TryBuild than
+	// BuildOptions.Limit allows.
+	ErrTooLong = errors.New("too many values")
+
+	// ErrNilValue indicates that a value passed to TryBuild is nil, when
+	// BuildOptions.Nil is NilError.
+	ErrNilValue = errors.New("nil value")
+)
+
+// NilPolicy determines how BuildOptions treats nil values, meaning nil
+// interface values rather than typed nils such as nil pointers.
+type NilPolicy int
+
+const (
+	// NilWrap chains nil values like any other, as Build does.
+	NilWrap NilPolicy = iota
+
+	// NilSkip drops nil values, so they are not part of the chain.
+	NilSkip
+
+	// NilError makes nil values an error.
+	NilError
+)
+
+// BuildOptions configures how a chain is built. The zero value builds chains
+// exactly like Build.
+type BuildOptions struct {
+	// MaxLen caps the number of values in the built chain. If more values are
+	// passed, the innermost (oldest) values are dropped so that only the last
+	// MaxLen values are chained. Dropped values are not reachable from the
+	// returned chain. Zero means unlimited.
+	MaxLen int
+
+	// Limit, if positive, caps the number of values that may be passed.
+	// Unlike MaxLen, which drops the excess values, passing more than Limit
+	// values is an error, and nothing is built. It is checked before any
+	// other option is applied, which protects against building chains from
+	// unbounded input. Zero means unlimited.
+	Limit int
+
+	// IDFunc, if set, is called to assign an ID to each link that Build
+	// synthesizes, where index is the position of the link's value among the
+	// chained values, starting from 0 for the innermost. This is its
+	// position after the other options have reordered, skipped or dropped
+	// values, so it may differ from its position among the values passed,
+	// which is the one errors refer to. The ID can be retrieved through the
+	// link's ID method. IDs are metadata only and are not considered by Is
+	// or As unless queried explicitly.
+	IDFunc func(index int) string
+
+	// Meta, if set, is called to attach metadata to each link that Build
+	// synthesizes, where index is as for IDFunc. The metadata can be
+	// retrieved with the Meta function. Metadata is not considered by Is or
+	// As.
+	Meta func(index int) map[string]interface{}
+
+	// AllowedTypes, if not empty, restricts the values that can be chained to
+	// those assignable to one of the types pointed to by its elements, which
+	// are typically of the form (*T)(nil). Assignability is checked using
+	// x.TypeExample. A nil value is allowed if one of the types is nillable.
+	AllowedTypes []interface{}
+
+	// Nil determines how nil values are treated. By default, they are
+	// chained like any other value. If Nil is NilSkip and every value is
+	// nil, TryBuild returns nil and no error.
+	Nil NilPolicy
+
+	// OutermostFirst makes Build treat its first argument as the outermost
+	// value and its last as the innermost, so that Build(a, b, c) reads as
+	// "a wraps b wraps c". By default, the first argument is the innermost.
+	// If SortBy is set, it determines the order instead, and OutermostFirst
+	// only affects the order of values that compare equal.
+	OutermostFirst bool
+
+	// SortBy, if set, sorts the values before they are chained, so that the
+	// innermost value is the smallest according to the less function. Values
+	// that compare equal keep their relative order. Sorting determines which
+	// values are innermost and outermost, and so which are dropped by MaxLen.
+	// By default, values are chained in the order they are passed.
+	SortBy func(a, b interface{}) bool
+
+	// KeyFunc, if set, deduplicates the values by key before they are
+	// chained. Of the values with the same key, only the outermost (the last
+	// passed, or the greatest if SortBy is set) is kept, and the rest are
+	// dropped. Keys are compared with ==, so they must be comparable. By
+	// default, no values are dropped.
+	KeyFunc func(v interface{}) interface{}
+
+	// CompactLinks allocates the links that Build synthesizes together in a
+	// single block, rather than individually. This reduces the number of
+	// allocations for long chains, but the whole block is retained as long
+	// as any link in the chain is reachable. It is purely an internal
+	// representation detail and does not change the behavior of the chain.
+	CompactLinks bool
+
+	// Pool, if set, is used to obtain the links that Build synthesizes, so
+	// that links returned to it by Release can be reused. The pool must only
+	// be used for this purpose. Pool is ignored if CompactLinks is set.
+	Pool *sync.Pool
+
+	// Wrapper, if set, is called to create the wrapper for each value that
+	// cannot wrap the value before it, in place of the link Build would
+	// synthesize. The wrapper is passed v, which it is expected to hold, and
+	// its Wrap method is then called with the value before it, which must
+	// return true. CompactLinks, Pool, IDFunc and Meta only apply to
+	// synthesized links, so they are ignored for wrappers.
+	//
+	// Unlike synthesized links, wrappers are elements of the chain in their
+	// own right, so they should usually implement Is and As to delegate to
+	// the value they hold, as Link does.
+	Wrapper func(v interface{}) iface.Wrap
+
+	// Strict makes it an error for a value that implements Wrap to return
+	// false from its Wrap method, rather than wrapping it with a synthesized
+	// link as Build does. Values that do not implement Wrap are still
+	// wrapped. Since the values before the one that refused have already
+	// been wrapped, the error leaves them partially chained.
+	Strict bool
+}
+
+// Build chains together vals using the rules described by Build, after
+// applying the options in o.
+//
+// If vals is empty, or if vals violates the options, this will panic.
+func (o BuildOptions) Build(vals ...interface{}) interface{} {
+	v, err := o.TryBuild(vals...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return v
+}
+
+// TryBuild is like Build, but returns an error rather than panicking if vals
+// violates the options. If vals is empty, this will still panic.
+func (o BuildOptions) TryBuild(vals ...interface{}) (interface{}, error) {
+	if len(vals) == 0 {
+		panic("chain: Build called with zero arguments")
+	}
+
+	if o.Limit > 0 && len(vals) > o.Limit {
+		return nil, fmt.Errorf("chain: %d values exceed limit of %d: %w", len(vals), o.Limit, ErrTooLong)
+	}
+
+	if o.Nil == NilError {
+		for i, v := range vals {
+			if v == nil {
+				return nil, fmt.Errorf("chain: value %d: %w", i, ErrNilValue)
+			}
+		}
+	}
+
+	if err := o.checkTypes(vals); err != nil {
+		return nil, err
+	}
+
+	args := o.args(vals)
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	if o.OutermostFirst {
+		for i, j := 0, len(args)-1; i < j; i, j = i+1, j-1 {
+			args[i], args[j] = args[j], args[i]
+		}
+	}
+
+	if o.SortBy != nil {
+		sort.SliceStable(args, func(i, j int) bool {
+			return o.SortBy(args[i].v, args[j].v)
+		})
+	}
+
+	if o.KeyFunc != nil {
+		args = o.dedup(args)
+	}
+
+	if o.MaxLen > 0 && len(args) > o.MaxLen {
+		args = args[len(args)-o.MaxLen:]
+	}
+
+	return o.link(args)
+}
+
+// arg is a value passed to TryBuild, along with its position among the
+// arguments, so that errors can name it however the values are reordered.
+type arg struct {
+	v interface{}
+	i int
+}
+
+// args returns vals as a new slice of args, without the nil values if they
+// are to be skipped.
+func (o BuildOptions) args(vals []interface{}) []arg {
+	args := make([]arg, 0, len(vals))
+	for i, v := range vals {
+		if v == nil && o.Nil == NilSkip {
+			continue
+		}
+		args = append(args, arg{v: v, i: i})
+	}
+	return args
+}
+
+// checkTypes returns an error naming the first value in vals that is not
+// allowed by AllowedTypes. Nil values that are to be skipped are not checked.
+func (o BuildOptions) checkTypes(vals []interface{}) error {
+	if len(o.AllowedTypes) == 0 {
+		return nil
+	}
+
+	exs := make([]x.TypeExample, len(o.AllowedTypes))
+	for i, t := range o.AllowedTypes {
+		ex, err := x.MakeTypeExample(t)
+		if err != nil {
+			return fmt.Errorf("chain: AllowedTypes[%d] %w", i, err)
+		}
+		exs[i] = ex
+	}
+
+	for i, v := range vals {
+		if v == nil && o.Nil == NilSkip {
+			continue
+		}
+		if !allowed(exs, v) {
+			return fmt.Errorf("chain: value %d of type %v: %w", i, reflect.TypeOf(v), ErrTypeNotAllowed)
+		}
+	}
+	return nil
+}
+
+// dedup returns the args with the outermost value for each key, preserving
+// their order.
+func (o BuildOptions) dedup(args []arg) []arg {
+	seen := make(map[interface{}]struct{})
+	kept := make([]arg, len(args))
+	n := len(kept)
+	for i := len(args) - 1; i >= 0; i-- {
+		k := o.KeyFunc(args[i].v)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		n--
+		kept[n] = args[i]
+	}
+	return kept[n:]
+}
+
+func allowed(exs []x.TypeExample, v interface{}) bool {
+	for _, ex := range exs {
+		if v == nil {
+			switch ex.Type().Kind() {
+			case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+				return true
+			}
+			continue
+		}
+
+		if ex.AssignableFrom(reflect.TypeOf(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+// link chains together the values of args, which must not be empty.
+func (o BuildOptions) link(args []arg) (interface{}, error) {
+	var block []buildLink
+	if o.CompactLinks {
+		block = make([]buildLink, len(args)-1)
+	}
+
+	src := args[0].v
+	for i := 1; i < len(args); i++ {
+		dst := args[i].v
+
+		if w, ok := dst.(iface.Wrap); ok {
+			if w.Wrap(src) {
+				src = dst
+				continue
+			}
+			if o.Strict {
+				return nil, fmt.Errorf("chain: value %d of type %v: %w", args[i].i, reflect.TypeOf(dst), ErrWrapRefused)
+			}
+		}
+
+		if o.Wrapper != nil {
+			w := o.Wrapper(dst)
+			if !w.Wrap(src) {
+				return nil, fmt.Errorf("chain: wrapper of type %T for value %d: %w", w, args[i].i, ErrWrapRefused)
+			}
+			src = w
+			continue
+		}
+
+		var link *buildLink
+		switch {
+		case block != nil:
+			link = &block[i-1]
+		case o.Pool != nil:
+			link, _ = o.Pool.Get().(*buildLink)
+			if link == nil {
+				link = &buildLink{}
+			}
+			link.pool = o.Pool
+		default:
+			link = &buildLink{}
+		}
+		link.Link.Set(dst)
+		if o.IDFunc != nil {
+			link.Link.SetID(o.IDFunc(i))
+		}
+		if o.Meta != nil {
+			link.Link.SetMeta(o.Meta(i))
+		}
+		if !link.Wrap(src) {
+			panic("Link.Wrap should always return true")
+		}
+
+		src = link
+	}
+
+	return src, nil
+}
+
+// BuildWith is like Build, but wraps each value that cannot wrap the value
+// before it with the result of calling factory, rather than a link that
+// Build synthesizes, as described by BuildOptions.Wrapper.
+//
+// If vals is empty, or a wrapper's Wrap method returns false, this will
+// panic.
+func BuildWith(factory func(v interface{}) iface.Wrap, vals ...interface{}) interface{} {
+	return BuildOptions{Wrapper: factory}.Build(vals...)
+}
+
+// FromSlice chains together vals like Build, with vals[0] as the innermost
+// value, but returns nil rather than panicking if vals is empty.
+func FromSlice(vals []interface{}) interface{} {
+	if len(vals) == 0 {
+		return nil
+	}
+	return Build(vals...)
+}
+
+// BuildSlice chains together vals like Build, with vals[0] as the innermost
+// value, returning the outermost value and true. If vals is empty, BuildSlice
+// returns nil and false rather than panicking.
+func BuildSlice(vals []interface{}) (interface{}, bool) {
+	if len(vals) == 0 {
+		return nil, false
+	}
+	return Build(vals...), true
+}
+
+// Unbuild decomposes v's chain into the values it was built from, innermost
+// first, so that Build(Unbuild(v)...) builds an equivalent chain. Links
+// synthesized by Build are represented by the values they hold.
+//
+// Unlike Slice, Unbuild does not descend into branches: a value implementing
+// iface.UnwrapMulti, such as one returned by Join, is returned as is, since
+// it still holds its children.
+func Unbuild(v interface{}) []interface{} {
+	var vals []interface{}
+	for {
+		vals = append(vals, elem(v))
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return reversed(vals)
+		}
+	}
+}
+
+// Meta returns the metadata attached to v, if v is a link with metadata, such
+// as one synthesized by Build with BuildOptions.Meta. Only v itself is
+// considered, not the rest of its chain, so metadata for inner links can be
+// retrieved by passing each node obtained from Unwrap or WalkWithParent.
+func Meta(v interface{}) (map[string]interface{}, bool) {
+	m, ok := v.(interface {
+		Meta() (map[string]interface{}, bool)
+	})
+	if !ok {
+		return nil, false
+	}
+	return m.Meta()
+}
+
+// Release returns the links in v's chain that were obtained from
+// BuildOptions.Pool to their pool, so they can be reused by later builds.
+//
+// Released links are reset, so neither v nor any other reference to a link in
+// its chain may be used after calling Release. Pooling is therefore unsafe if
+// any part of the chain may still be referenced elsewhere.
+func Release(v interface{}) {
+	for {
+		next, ok := Unwrap(v)
+		if l, isLink := v.(*buildLink); isLink && l.pool != nil {
+			pool := l.pool
+			*l = buildLink{}
+			pool.Put(l)
+		}
+
+		if !ok {
+			return
+		}
+		v = next
+	}
+}
+
+// BuildLazy returns a chain consisting of head followed by the chain returned
+// by tail. The tail function is not called until the chain is first unwrapped
+// past head, for example by Is or As failing to match head, and its result is
+// cached for subsequent calls. If tail is nil or returns nil, head is the
+// innermost value.
+//
+// The chain may be used from multiple goroutines: tail is called at most
+// once, and concurrent unwrappers wait for it to return.
+func BuildLazy(head interface{}, tail func() interface{}) interface{} {
+	return &lazyLink{v: head, tail: tail}
+}
+
+type lazyLink struct {
+	v    interface{}
+	once sync.Once
+	tail func() interface{}
+	next Holder
+}
+
+func (l *lazyLink) Unwrap() (interface{}, bool) {
+	l.once.Do(func() {
+		if l.tail == nil {
+			return
+		}
+		if next := l.tail(); next != nil {
+			l.next.Set(next)
+		}
+		l.tail = nil
+	})
+	return l.next.Get()
+}
+
+// Is matches the held value as buildLink.Is does.
+func (l *lazyLink) Is(target interface{}) bool {
+	return match(l.v, target)
+}
+
+func (l *lazyLink) As(target interface{}) bool {
+	return As(l.v, target)
+}
+
+// Interleave builds a chain by alternating the values of a's chain and b's
+// chain, from the outermost of each: a0, b0, a1, b1, and so on, where a0 is
+// the outermost value of the result. Once the shorter chain is exhausted, the
+// remaining values of the longer chain follow in order. A nil a or b is
+// treated as an empty chain, and if both are nil, Interleave returns nil.
+//
+// The values are those returned by Unbuild, so branches, such as those
+// created by Join, are kept whole. Neither a nor b is modified: the values
+// are chained as described for Map.
+func Interleave(a, b interface{}) interface{} {
+	var as, bs []interface{}
+	if a != nil {
+		as = reversed(Unbuild(a))
+	}
+	if b != nil {
+		bs = reversed(Unbuild(b))
+	}
+
+	vals := make([]interface{}, 0, len(as)+len(bs))
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		if i < len(as) {
+			vals = append(vals, as[i])
+		}
+		if i < len(bs) {
+			vals = append(vals, bs[i])
+		}
+	}
+
+	if len(vals) == 0 {
+		return nil
+	}
+	return relink(reversed(vals))
+}
+
+// reversed returns a copy of vals in reverse order.
+func reversed(vals []interface{}) []interface{} {
+	r := make([]interface{}, len(vals))
+	for i, v := range vals {
+		r[len(vals)-1-i] = v
+	}
+	return r
+}
+
+// BuildTerminated is like Build, but chains terminator as the innermost value,
+// ahead of vals, so that walking the chain always ends at a known sentinel,
+// which Root returns.
+// The terminator is an ordinary value that takes part in Is and As like any
+// other. If vals is empty, terminator is returned.
+func BuildTerminated(terminator interface{}, vals ...interface{}) interface{} {
+	return Build(append([]interface{}{terminator}, vals...)...)
+}
+
+// IsTerminated reports whether the innermost value in v's chain matches
+// terminator, using the rules described by Is.
+func IsTerminated(v, terminator interface{}) bool {
+	return match(Root(v), terminator)
+}
+
+// Join returns a value that wraps each of vals as a separate child, in order,
+// so that v's chain branches into the chain of each of them. Nil values are
+// discarded, and Join returns nil if every value is nil or vals is empty.
+//
+// The returned value implements iface.UnwrapMulti, and is an element of the
+// chain in its own right, visited immediately before its children.
+func Join(vals ...interface{}) interface{} {
+	j := &joined{}
+	for _, v := range vals {
+		if v != nil {
+			j.children = append(j.children, v)
+		}
+	}
+	if len(j.children) == 0 {
+		return nil
+	}
+	return j
+}
+
+type joined struct {
+	children []interface{}
+}
+
+func (j *joined) Unwrap() []interface{} {
+	return j.children
+}
@@ -0,0 +1,504 @@
+package chain_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/iface"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestBuildOptionsMaxLen(t *testing.T) {
+	assert.Panics(t, "chain: Build called with zero arguments", func() {
+		chain.BuildOptions{MaxLen: 2}.Build()
+	})
+
+	ch1 := chain.BuildOptions{}.Build("a", "b", "c")
+	assert.True(t, chain.Is(ch1, "a"))
+	assert.True(t, chain.Is(ch1, "c"))
+
+	ch2 := chain.BuildOptions{MaxLen: 2}.Build("a", "b", "c")
+	assert.False(t, chain.Is(ch2, "a"))
+	assert.True(t, chain.Is(ch2, "b"))
+	assert.True(t, chain.Is(ch2, "c"))
+
+	var s string
+	assert.True(t, chain.As(ch2, &s))
+	assert.Equals(t, "c", s)
+
+	ch3 := chain.BuildOptions{MaxLen: 1}.Build("a", "b", "c")
+	assert.True(t, ch3 == "c")
+}
+
+func TestBuildOptionsLimit(t *testing.T) {
+	opts := chain.BuildOptions{Limit: 2}
+
+	ch, err := opts.TryBuild("a", "b")
+	assert.Ok(t, err)
+	assert.Equals(t, []interface{}{"b", "a"}, chain.Slice(ch))
+
+	_, err = opts.TryBuild("a", "b", "c")
+	assert.True(t, errors.Is(err, chain.ErrTooLong))
+	assert.Equals(t, "chain: 3 values exceed limit of 2: too many values", err.Error())
+
+	opts.MaxLen = 1
+	_, err = opts.TryBuild("a", "b", "c")
+	assert.True(t, errors.Is(err, chain.ErrTooLong))
+}
+
+func TestBuildOptionsIDFunc(t *testing.T) {
+	type identified interface {
+		ID() (string, bool)
+	}
+
+	ch := chain.BuildOptions{
+		IDFunc: func(i int) string { return fmt.Sprintf("id%d", i) },
+	}.Build("a", "b", "c")
+
+	var ids []string
+	for v, ok := ch, true; ok; v, ok = chain.Unwrap(v) {
+		if l, ok := v.(identified); ok {
+			id, ok := l.ID()
+			assert.True(t, ok)
+			ids = append(ids, id)
+		}
+	}
+	assert.Equals(t, []string{"id2", "id1"}, ids)
+
+	// IDs don't take part in matching
+	assert.False(t, chain.Is(ch, "id2"))
+
+	l, ok := chain.Build("a", "b").(identified)
+	assert.True(t, ok)
+	_, ok = l.ID()
+	assert.False(t, ok)
+
+	id, ok := (&chain.Link{}).SetID("x").ID()
+	assert.True(t, ok)
+	assert.Equals(t, "x", id)
+}
+
+func TestBuildOptionsAllowedTypes(t *testing.T) {
+	opts := chain.BuildOptions{
+		AllowedTypes: []interface{}{(*string)(nil), (*fmt.Stringer)(nil)},
+	}
+
+	ch, err := opts.TryBuild("a", time.Second, nil, "b")
+	assert.Ok(t, err)
+	assert.True(t, chain.Is(ch, time.Second))
+
+	_, err = opts.TryBuild("a", time.Second, 3)
+	assert.True(t, errors.Is(err, chain.ErrTypeNotAllowed))
+	assert.Equals(t, "chain: value 2 of type int: type not allowed", err.Error())
+
+	assert.Panics(t, "chain: value 0 of type float64: type not allowed", func() {
+		opts.Build(1.5)
+	})
+
+	_, err = chain.BuildOptions{AllowedTypes: []interface{}{1}}.TryBuild("a")
+	assert.Equals(t, "chain: AllowedTypes[0] must be a pointer", err.Error())
+
+	_, err = chain.BuildOptions{AllowedTypes: []interface{}{(*int)(nil)}}.TryBuild(nil)
+	assert.True(t, errors.Is(err, chain.ErrTypeNotAllowed))
+}
+
+type picky struct {
+	accept  bool
+	wrapped chain.Holder
+}
+
+func (p *picky) Wrap(v interface{}) bool {
+	if !p.accept {
+		return false
+	}
+	p.wrapped.Set(v)
+	return true
+}
+
+func (p *picky) Unwrap() (interface{}, bool) {
+	return p.wrapped.Get()
+}
+
+func TestBuildOptionsStrict(t *testing.T) {
+	opts := chain.BuildOptions{Strict: true}
+
+	accepting := &picky{accept: true}
+	ch, err := opts.TryBuild("a", accepting, "b")
+	assert.Ok(t, err)
+	assert.Equals(t, []interface{}{"b", accepting, "a"}, chain.Slice(ch))
+
+	_, err = opts.TryBuild("a", &picky{}, "b")
+	assert.True(t, errors.Is(err, chain.ErrWrapRefused))
+	assert.Equals(t, "chain: value 1 of type *chain_test.picky: wrap refused", err.Error())
+
+	assert.Panics(t, "chain: value 1 of type *chain_test.picky: wrap refused", func() {
+		opts.Build("a", &picky{})
+	})
+
+	refusing := &picky{}
+	ch = chain.Build("a", refusing)
+	assert.Equals(t, []interface{}{refusing, "a"}, chain.Slice(ch))
+
+	_, err = chain.BuildOptions{Strict: true, OutermostFirst: true}.TryBuild(&picky{}, "b", "c")
+	assert.Equals(t, "chain: value 0 of type *chain_test.picky: wrap refused", err.Error())
+
+	_, err = chain.BuildOptions{Strict: true, Nil: chain.NilSkip}.TryBuild(nil, "a", &picky{})
+	assert.Equals(t, "chain: value 2 of type *chain_test.picky: wrap refused", err.Error())
+}
+
+func TestBuildOptionsOutermostFirst(t *testing.T) {
+	opts := chain.BuildOptions{OutermostFirst: true}
+	vals := []interface{}{"a", "b", "c"}
+
+	ch := opts.Build(vals...)
+	assert.Equals(t, []interface{}{"a", "b", "c"}, chain.Slice(ch))
+	assert.Equals(t, []interface{}{"a", "b", "c"}, vals)
+
+	opts.MaxLen = 2
+	assert.Equals(t, []interface{}{"a", "b"}, chain.Slice(opts.Build(vals...)))
+
+	assert.Equals(t, "a", opts.Build("a"))
+}
+
+type tagged struct {
+	chain.Link
+	tag string
+}
+
+func TestBuildWith(t *testing.T) {
+	n := 0
+	factory := func(v interface{}) iface.Wrap {
+		n++
+		w := &tagged{tag: fmt.Sprint("tag", n)}
+		w.Set(v)
+		return w
+	}
+
+	accepting := &picky{accept: true}
+	ch := chain.BuildWith(factory, "a", "b", accepting, "c")
+	assert.Equals(t, 2, n)
+	assert.True(t, chain.Is(ch, "a"))
+	assert.True(t, chain.Is(ch, "b"))
+
+	var w *tagged
+	assert.True(t, chain.As(ch, &w))
+	assert.Equals(t, "tag2", w.tag)
+
+	var s string
+	assert.True(t, chain.As(ch, &s))
+	assert.Equals(t, "c", s)
+
+	assert.Panics(t, "chain: wrapper of type *chain_test.picky for value 1: wrap refused", func() {
+		chain.BuildWith(func(interface{}) iface.Wrap { return &picky{} }, "a", "b")
+	})
+}
+
+func TestBuildOptionsNil(t *testing.T) {
+	ch := chain.BuildOptions{}.Build("a", nil, "b")
+	assert.Equals(t, []interface{}{"b", nil, "a"}, chain.Slice(ch))
+
+	skip := chain.BuildOptions{Nil: chain.NilSkip}
+	ch = skip.Build(nil, "a", nil, "b", nil)
+	assert.Equals(t, []interface{}{"b", "a"}, chain.Slice(ch))
+
+	var np *int
+	ch = skip.Build("a", np)
+	assert.Equals(t, []interface{}{np, "a"}, chain.Slice(ch))
+
+	ch, err := skip.TryBuild(nil, nil)
+	assert.Ok(t, err)
+	assert.Equals(t, nil, ch)
+
+	_, err = chain.BuildOptions{Nil: chain.NilError}.TryBuild("a", nil)
+	assert.True(t, errors.Is(err, chain.ErrNilValue))
+	assert.Equals(t, "chain: value 1: nil value", err.Error())
+
+	typed := chain.BuildOptions{Nil: chain.NilSkip, AllowedTypes: []interface{}{(*string)(nil)}}
+	ch, err = typed.TryBuild("a", nil, "b")
+	assert.Ok(t, err)
+	assert.Equals(t, []interface{}{"b", "a"}, chain.Slice(ch))
+
+	_, err = typed.TryBuild("a", nil, 1)
+	assert.Equals(t, "chain: value 2 of type int: type not allowed", err.Error())
+
+	typed.Nil = chain.NilError
+	_, err = typed.TryBuild("a", nil, "b")
+	assert.True(t, errors.Is(err, chain.ErrNilValue))
+}
+
+func TestBuildOptionsSortBy(t *testing.T) {
+	vals := []interface{}{3, 1, 2}
+	opts := chain.BuildOptions{
+		SortBy: func(a, b interface{}) bool { return a.(int) < b.(int) },
+	}
+
+	var i int
+	assert.True(t, chain.As(opts.Build(vals...), &i))
+	assert.Equals(t, 3, i)
+	assert.Equals(t, []interface{}{3, 1, 2}, vals)
+
+	opts.MaxLen = 2
+	ch := opts.Build(vals...)
+	assert.False(t, chain.Is(ch, 1))
+	assert.True(t, chain.Is(ch, 2))
+}
+
+func TestBuildOptionsCompactLinks(t *testing.T) {
+	vals := []interface{}{"a", 1, "b", &unwrappable{}, 2.5}
+	loose := chain.Build(vals...)
+	compact := chain.BuildOptions{CompactLinks: true}.Build(vals...)
+
+	for v, c := loose, compact; ; {
+		var vs, cs string
+		assert.Equals(t, chain.As(v, &vs), chain.As(c, &cs))
+		assert.Equals(t, vs, cs)
+		for _, target := range vals {
+			assert.Equals(t, chain.Is(v, target), chain.Is(c, target))
+		}
+
+		var vok, cok bool
+		v, vok = chain.Unwrap(v)
+		c, cok = chain.Unwrap(c)
+		assert.Equals(t, vok, cok)
+		if !vok {
+			break
+		}
+	}
+}
+
+func benchmarkBuild(b *testing.B, opts chain.BuildOptions) {
+	vals := make([]interface{}, 1000)
+	for i := range vals {
+		vals[i] = i
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		opts.Build(vals...)
+	}
+}
+
+func BenchmarkBuild(b *testing.B) {
+	benchmarkBuild(b, chain.BuildOptions{})
+}
+
+func BenchmarkBuildCompactLinks(b *testing.B) {
+	benchmarkBuild(b, chain.BuildOptions{CompactLinks: true})
+}
+
+func TestBuildLazy(t *testing.T) {
+	calls := 0
+	ch := chain.BuildLazy("a", func() interface{} {
+		calls++
+		return chain.Build("c", "b")
+	})
+
+	assert.True(t, chain.Is(ch, "a"))
+	var s string
+	assert.True(t, chain.As(ch, &s))
+	assert.Equals(t, "a", s)
+	assert.Equals(t, 0, calls)
+
+	assert.True(t, chain.Is(ch, "b"))
+	assert.True(t, chain.Is(ch, "c"))
+	assert.False(t, chain.Is(ch, "d"))
+	assert.Equals(t, 1, calls)
+
+	_, ok := chain.Unwrap(chain.BuildLazy("a", nil))
+	assert.False(t, ok)
+	_, ok = chain.Unwrap(chain.BuildLazy("a", func() interface{} { return nil }))
+	assert.False(t, ok)
+}
+
+func TestBuildOptionsPool(t *testing.T) {
+	pool := &sync.Pool{}
+	opts := chain.BuildOptions{Pool: pool}
+
+	ch := opts.Build("a", "b", "c")
+	assert.True(t, chain.Is(ch, "a"))
+	assert.True(t, chain.Is(ch, "c"))
+	chain.Release(ch)
+
+	ch = opts.Build("x", "y")
+	assert.True(t, chain.Is(ch, "x"))
+	assert.True(t, chain.Is(ch, "y"))
+	assert.False(t, chain.Is(ch, "a"))
+	chain.Release(ch)
+
+	// values that aren't pooled links are left alone
+	chain.Release(chain.Build("a", "b"))
+	chain.Release("a")
+}
+
+func BenchmarkBuildChurn(b *testing.B) {
+	vals := []interface{}{1, 2, 3, 4, 5, 6, 7, 8}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		chain.Build(vals...)
+	}
+}
+
+func BenchmarkBuildChurnPool(b *testing.B) {
+	vals := []interface{}{1, 2, 3, 4, 5, 6, 7, 8}
+	opts := chain.BuildOptions{Pool: &sync.Pool{}}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		chain.Release(opts.Build(vals...))
+	}
+}
+
+func TestBuildOptionsMeta(t *testing.T) {
+	ch := chain.BuildOptions{
+		Meta: func(i int) map[string]interface{} {
+			return map[string]interface{}{"index": i}
+		},
+	}.Build("a", "b", "c")
+
+	m, ok := chain.Meta(ch)
+	assert.True(t, ok)
+	assert.Equals(t, map[string]interface{}{"index": 2}, m)
+	assert.False(t, chain.Is(ch, m))
+
+	var metas []interface{}
+	chain.WalkWithParent(ch, func(v, parent interface{}) bool {
+		if m, ok := chain.Meta(parent); ok {
+			metas = append(metas, m["index"])
+		}
+		return true
+	})
+	assert.Equals(t, []interface{}{2, 1}, metas)
+
+	_, ok = chain.Meta(chain.Build("a", "b"))
+	assert.False(t, ok)
+	_, ok = chain.Meta("a")
+	assert.False(t, ok)
+}
+
+func TestInterleave(t *testing.T) {
+	slice := func(v interface{}) []interface{} {
+		var vals []interface{}
+		chain.WalkWithParent(v, func(v, _ interface{}) bool {
+			vals = append(vals, v)
+			return true
+		})
+		return vals
+	}
+
+	a := chain.Build("a2", "a1", "a0")
+	b := chain.Build("b0")
+	assert.Equals(t, []interface{}{"a0", "b0", "a1", "a2"}, slice(chain.Interleave(a, b)))
+	assert.Equals(t, []interface{}{"b0", "a0", "a1", "a2"}, slice(chain.Interleave(b, a)))
+	assert.Equals(t, []interface{}{"a0", "a1", "a2"}, slice(chain.Interleave(a, nil)))
+	assert.Equals(t, []interface{}{"b0"}, slice(chain.Interleave(nil, b)))
+	assert.Equals(t, nil, chain.Interleave(nil, nil))
+
+	l0 := (&chain.Link{}).Set("l0")
+	l1 := (&chain.Link{}).Set("l1")
+	a = chain.Build(l1, l0)
+	b = chain.Build("r1", "r0")
+	v := chain.Interleave(a, b)
+	assert.Equals(t, []interface{}{l0, l1}, chain.Slice(a))
+	assert.Equals(t, []interface{}{"r0", "r1"}, chain.Slice(b))
+	assert.Equals(t, 4, chain.Len(v))
+	for i, want := range []string{"l0", "r0", "l1", "r1"} {
+		e, _ := chain.Nth(v, i)
+		assert.True(t, chain.Is(e, want))
+	}
+}
+
+func TestBuildTerminated(t *testing.T) {
+	type end struct{}
+
+	ch := chain.BuildTerminated(end{}, "a", "b")
+	assert.True(t, chain.Is(ch, end{}))
+	assert.True(t, chain.Is(ch, "a"))
+	assert.True(t, chain.IsTerminated(ch, end{}))
+	assert.False(t, chain.IsTerminated(ch, "a"))
+	assert.False(t, chain.IsTerminated(chain.Build("a", end{}), end{}))
+
+	assert.Equals(t, end{}, chain.BuildTerminated(end{}))
+	assert.True(t, chain.IsTerminated(end{}, end{}))
+}
+
+func TestBuildOptionsKeyFunc(t *testing.T) {
+	type setting struct {
+		Key, Value string
+	}
+
+	opts := chain.BuildOptions{
+		KeyFunc: func(v interface{}) interface{} { return v.(setting).Key },
+	}
+	ch := opts.Build(
+		setting{"a", "1"},
+		setting{"b", "2"},
+		setting{"a", "3"},
+		setting{"c", "4"},
+	)
+
+	assert.True(t, chain.Is(ch, setting{"a", "3"}))
+	assert.False(t, chain.Is(ch, setting{"a", "1"}))
+	assert.Equals(t, 3, len(chain.AsFromHere[setting](ch)))
+	assert.Equals(t, []setting{{"c", "4"}, {"a", "3"}, {"b", "2"}}, chain.AsFromHere[setting](ch))
+}
+
+func TestFromSlice(t *testing.T) {
+	assert.Equals(t, nil, chain.FromSlice(nil))
+	assert.Equals(t, nil, chain.FromSlice([]interface{}{}))
+	assert.Equals(t, "a", chain.FromSlice([]interface{}{"a"}))
+	assert.Equals(t, []interface{}{"c", "b", "a"}, chain.Slice(chain.FromSlice([]interface{}{"a", "b", "c"})))
+}
+
+func TestBuildSlice(t *testing.T) {
+	v, ok := chain.BuildSlice(nil)
+	assert.False(t, ok)
+	assert.Equals(t, nil, v)
+
+	v, ok = chain.BuildSlice([]interface{}{nil})
+	assert.True(t, ok)
+	assert.Equals(t, nil, v)
+
+	v, ok = chain.BuildSlice([]interface{}{"a", "b", "c"})
+	assert.True(t, ok)
+	assert.Equals(t, []interface{}{"c", "b", "a"}, chain.Slice(v))
+}
+
+func TestUnbuild(t *testing.T) {
+	vals := []interface{}{"a", 1, nil, "c"}
+	ch := chain.Build(vals...)
+	assert.Equals(t, vals, chain.Unbuild(ch))
+	assert.Equals(t, chain.Slice(ch), chain.Slice(chain.Build(chain.Unbuild(ch)...)))
+
+	j := chain.Join("x", "y")
+	ch = chain.Build("a", j, "b")
+	assert.Equals(t, []interface{}{"a", j, "b"}, chain.Unbuild(ch))
+	assert.Equals(t, chain.Slice(ch), chain.Slice(chain.Build(chain.Unbuild(ch)...)))
+
+	assert.Equals(t, []interface{}{"a"}, chain.Unbuild("a"))
+}
+
+func TestJoin(t *testing.T) {
+	a := chain.Build("a1", "a2")
+	j := chain.Join(a, nil, "b")
+	assert.Implements(t, (*iface.UnwrapMulti)(nil), j)
+	assert.Equals(t, []interface{}{a, "b"}, chain.UnwrapAll(j))
+
+	assert.True(t, chain.Is(j, "a1"))
+	assert.True(t, chain.Is(j, "b"))
+	assert.False(t, chain.Is(j, "c"))
+	assert.Equals(t, []interface{}{j, "a2", "a1", "b"}, chain.Slice(j))
+
+	outer := chain.Build(j, "x")
+	var s string
+	assert.True(t, chain.As(outer, &s))
+	assert.Equals(t, "x", s)
+	assert.True(t, chain.Is(outer, "a1"))
+
+	assert.Equals(t, nil, chain.Join())
+	assert.Equals(t, nil, chain.Join(nil, nil))
+}
@@ -7,6 +7,7 @@ package chain
 
 import (
 	"reflect"
+	"sync"
 
 	"github.com/rbranson/chain/iface"
 	"github.com/rbranson/chain/x"
@@ -14,6 +15,10 @@ import (
 
 // Unwrap returns the result of calling the Unwrap method on v, if v's type
 // implements iface.Unwrap. Otherwise, Unwrap returns nil and false.
+//
+// Unwrap only calls a method of the form Unwrap() (interface{}, bool). In
+// particular, it does not unwrap values implementing iface.UnwrapMulti, whose
+// children can be obtained with UnwrapAll.
 func Unwrap(v interface{}) (interface{}, bool) {
 	u, ok := v.(iface.Unwrap)
 	if !ok {
@@ -22,6 +27,20 @@ func Unwrap(v interface{}) (interface{}, bool) {
 	return u.Unwrap()
 }
 
+// UnwrapAll returns the values directly wrapped by v. If v's type implements
+// iface.UnwrapMulti, these are the result of calling its Unwrap method.
+// Otherwise, the result is the single value returned by Unwrap, if any, or
+// nil.
+func UnwrapAll(v interface{}) []interface{} {
+	if m, ok := v.(iface.UnwrapMulti); ok {
+		return m.Unwrap()
+	}
+	if next, ok := Unwrap(v); ok {
+		return []interface{}{next}
+	}
+	return nil
+}
+
 // Is reports whether any value in v's chain matches target.
 //
 // The chain consists of v itself followed by the sequence of values obtained
@@ -38,22 +57,37 @@ func Unwrap(v interface{}) (interface{}, bool) {
 //    }
 //
 // then Is(MyValue{}, "foo") returns true.
+//
+// A chain may branch. If a value implements iface.UnwrapMulti, such as those
+// returned by Join, or Unwrap() []error, such as those returned by
+// errors.Join, the chain of each of its children is also searched, in order,
+// before continuing with the rest of the chain. The search is depth first,
+// in the order described by Walk.
 func Is(v interface{}, target interface{}) bool {
+	return is(v, target, nil)
+}
+
+// is implements Is, recording its work in st if st is not nil.
+func is(v interface{}, target interface{}, st *WalkStats) bool {
 	for {
+		if st != nil {
+			st.Steps++
+		}
+
+		if matchStats(v, target, st) {
+			return true
+		}
+
 		if x.Nil(v) && x.Nil(target) {
-			return reflect.TypeOf(v) == reflect.TypeOf(target)
+			return false
 		}
 
-		if isv, ok := v.(iface.Is); ok {
-			if isv.Is(target) {
+		for _, b := range branches(elem(v)) {
+			if is(b, target, st) {
 				return true
 			}
 		}
 
-		if reflect.DeepEqual(v, target) {
-			return true
-		}
-
 		var ok bool
 		v, ok = Unwrap(v)
 		if !ok {
@@ -62,6 +96,33 @@ func Is(v interface{}, target interface{}) bool {
 	}
 }
 
+// match reports whether the single value v matches target, using the rules
+// described by Is.
+func match(v interface{}, target interface{}) bool {
+	return matchStats(v, target, nil)
+}
+
+// matchStats implements match, recording its work in st if st is not nil.
+func matchStats(v interface{}, target interface{}, st *WalkStats) bool {
+	if x.Nil(v) && x.Nil(target) {
+		return reflect.TypeOf(v) == reflect.TypeOf(target)
+	}
+
+	if isv, ok := v.(iface.Is); ok {
+		if st != nil {
+			st.IsMethodCalled = true
+		}
+		if isv.Is(target) {
+			return true
+		}
+	}
+
+	if st != nil {
+		st.Comparisons++
+	}
+	return reflect.DeepEqual(v, target)
+}
+
 // As finds the first value in v's chain that matches target, and if so, sets
 // target to that value and returns true. Otherwise, it returns false.
 //
@@ -76,26 +137,24 @@ func Is(v interface{}, target interface{}) bool {
 // A value type might provide an As method so it can be treated as if it were
 // a different value type.
 //
+// Branching chains are searched depth first, as described by Is, so the
+// value target is set to is the first match in the order described by Walk.
+//
 // As panics if target is not a non-nil pointer.
 func As(v interface{}, target interface{}) bool {
-	targetVal, ok := x.ValueOf(target)
-	if !ok {
-		panic("chain: target must not be nil")
-	}
-
-	targetEx, err := x.MakeTypeExample(target)
-	if err != nil {
-		panic("chain: target " + err.Error())
-	}
+	targetVal, targetEx := asTarget(target)
+	return as(v, target, targetVal, targetEx)
+}
 
+// as implements As for a validated target.
+func as(v interface{}, target interface{}, targetVal reflect.Value, targetEx x.TypeExample) bool {
 	for {
-		if targetEx.AssignableFrom(reflect.TypeOf(v)) {
-			targetVal.Elem().Set(reflect.ValueOf(v))
+		if asOne(v, target, targetVal, targetEx) {
 			return true
 		}
 
-		if asv, ok := v.(iface.As); ok {
-			if asv.As(target) {
+		for _, b := range branches(elem(v)) {
+			if as(b, target, targetVal, targetEx) {
 				return true
 			}
 		}
@@ -108,10 +167,63 @@ func As(v interface{}, target interface{}) bool {
 	}
 }
 
+// branches returns the children of the element e, if e branches: the values
+// returned by its Unwrap method if it implements iface.UnwrapMulti or
+// iface.UnwrapErrors. Every search and walk of a chain visits the chains of
+// these children before continuing with the rest of e's chain.
+func branches(e interface{}) []interface{} {
+	switch b := e.(type) {
+	case iface.UnwrapMulti:
+		return b.Unwrap()
+	case iface.UnwrapErrors:
+		errs := b.Unwrap()
+		vals := make([]interface{}, len(errs))
+		for i, err := range errs {
+			vals[i] = err
+		}
+		return vals
+	}
+	return nil
+}
+
+// asTarget validates target as described by As, returning its value and type
+// example.
+func asTarget(target interface{}) (reflect.Value, x.TypeExample) {
+	targetVal, ok := x.ValueOf(target)
+	if !ok {
+		panic("chain: target must not be nil")
+	}
+
+	targetEx, err := x.MakeTypeExample(target)
+	if err != nil {
+		panic("chain: target " + err.Error())
+	}
+
+	return targetVal, targetEx
+}
+
+// asOne reports whether the single value v matches target, setting target if
+// so, using the rules described by As.
+func asOne(v interface{}, target interface{}, targetVal reflect.Value, targetEx x.TypeExample) bool {
+	if targetEx.AssignableFrom(reflect.TypeOf(v)) {
+		targetVal.Elem().Set(reflect.ValueOf(v))
+		return true
+	}
+
+	if asv, ok := v.(iface.As); ok {
+		if asv.As(target) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // use an internal type to prevent people from using Link to get at it
 // accidentally.
 type buildLink struct {
 	Link
+	pool *sync.Pool
 }
 
 func (l *buildLink) Wrap(v interface{}) bool {
@@ -122,8 +234,10 @@ func (l *buildLink) Unwrap() (interface{}, bool) {
 	return l.Link.Unwrap()
 }
 
+// Is matches the held value using the rules described by Is, so that the
+// link matches exactly what the element it represents does.
 func (l *buildLink) Is(target interface{}) bool {
-	return l.Link.Is(target)
+	return match(l.v, target)
 }
 
 func (l *buildLink) As(target interface{}) bool {
@@ -142,32 +256,7 @@ func (l *buildLink) As(target interface{}) bool {
 // continues. If the element does not implement Wrap, or Wrap returns false,
 // the value is wrapped with an unspecified type and then chaining continues.
 func Build(vals ...interface{}) interface{} {
-	switch len(vals) {
-	case 0:
-		panic("chain: Build called with zero arguments")
-	case 1:
-		return vals[0]
-	}
-
-	src := vals[0]
-	for i := 1; i < len(vals); i++ {
-		dst := vals[i]
-
-		if w, ok := dst.(iface.Wrap); ok && w.Wrap(src) {
-			src = dst
-			continue
-		}
-
-		link := &buildLink{}
-		link.Link.Set(dst)
-		if !link.Wrap(src) {
-			panic("Link.Wrap should always return true")
-		}
-
-		src = link
-	}
-
-	return src
+	return BuildOptions{}.Build(vals...)
 }
 
 // Holder holds an arbitrary Value and a positive assertion that it was
@@ -209,8 +298,10 @@ func Hold(v interface{}) Holder {
 //
 // It holds a value and wraps another value.
 type Link struct {
-	h Holder
-	v interface{}
+	h    Holder
+	v    interface{}
+	id   Holder
+	meta map[string]interface{}
 }
 
 // Set sets the Link's held value to v
@@ -219,6 +310,34 @@ func (l *Link) Set(v interface{}) *Link {
 	return l
 }
 
+// SetID sets the Link's ID to id. IDs are metadata only and are not
+// considered by Is or As.
+func (l *Link) SetID(id string) *Link {
+	l.id.Set(id)
+	return l
+}
+
+// ID returns the Link's ID, if one was set
+func (l *Link) ID() (string, bool) {
+	id, ok := l.id.Get()
+	if !ok {
+		return "", false
+	}
+	return id.(string), true
+}
+
+// SetMeta sets the Link's metadata to m. Metadata is not considered by Is or
+// As.
+func (l *Link) SetMeta(m map[string]interface{}) *Link {
+	l.meta = m
+	return l
+}
+
+// Meta returns the Link's metadata, if any was set
+func (l *Link) Meta() (map[string]interface{}, bool) {
+	return l.meta, l.meta != nil
+}
+
 // Unwrap unwraps the wrapped value
 func (l *Link) Unwrap() (interface{}, bool) {
 	return l.h.Get()
@@ -239,3 +358,9 @@ func (l *Link) Is(target interface{}) bool {
 func (l *Link) As(target interface{}) bool {
 	return As(l.v, target)
 }
+
+func (l *Link) detached() iface.Wrap {
+	c := *l
+	c.h = Holder{}
+	return &c
+}
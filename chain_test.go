@@ -1,6 +1,7 @@
 package chain_test
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -103,6 +104,51 @@ func TestIs(t *testing.T) {
 	assert.True(t, chain.Is(m2, &unwrappable2{}))
 }
 
+func TestIsJoinedErrors(t *testing.T) {
+	errA := errors.New("a")
+	errB := errors.New("b")
+	errC := errors.New("c")
+
+	joined := errors.Join(errA, errB)
+	assert.True(t, chain.Is(joined, errA))
+	assert.True(t, chain.Is(joined, errB))
+	assert.False(t, chain.Is(joined, errC))
+
+	ch := chain.Build("x", errors.Join(errC, errA), "y")
+	assert.True(t, chain.Is(ch, "x"))
+	assert.True(t, chain.Is(ch, errA))
+	assert.False(t, chain.Is(ch, errB))
+}
+
+type multi struct {
+	children []interface{}
+}
+
+func (m *multi) Unwrap() []interface{} {
+	return m.children
+}
+
+func TestUnwrapAll(t *testing.T) {
+	m := &multi{children: []interface{}{"a", "b"}}
+	assert.Equals(t, []interface{}{"a", "b"}, chain.UnwrapAll(m))
+	assert.Equals(t, []interface{}{"a"}, chain.UnwrapAll(chain.Build("a", "b")))
+	assert.Equals(t, []interface{}(nil), chain.UnwrapAll("a"))
+
+	_, ok := chain.Unwrap(m)
+	assert.False(t, ok)
+}
+
+func TestIsMulti(t *testing.T) {
+	m := &multi{children: []interface{}{chain.Build("a", "b"), "c"}}
+	assert.True(t, chain.Is(m, "a"))
+	assert.True(t, chain.Is(m, "c"))
+	assert.False(t, chain.Is(m, "d"))
+
+	ch := chain.Build("x", m, "y")
+	assert.True(t, chain.Is(ch, "a"))
+	assert.True(t, chain.Is(ch, "x"))
+}
+
 type asMatcher struct {
 	to interface{}
 }
@@ -202,6 +248,41 @@ func TestAs(t *testing.T) {
 	assert.Equals(t, "olleh", hs2)
 }
 
+func TestAsMulti(t *testing.T) {
+	m := &multi{children: []interface{}{"a", chain.Build(1, "b")}}
+
+	var i int
+	assert.True(t, chain.As(m, &i))
+	assert.Equals(t, 1, i)
+
+	var s string
+	assert.True(t, chain.As(chain.Build(2, m), &s))
+	assert.Equals(t, "a", s)
+
+	var f float64
+	assert.False(t, chain.As(m, &f))
+
+	// depth first: the whole first branch is searched before the second
+	deep := chain.Join(chain.Build(3, "c"), 4)
+	assert.True(t, chain.As(deep, &i))
+	assert.Equals(t, 3, i)
+}
+
+type codeError struct {
+	code int
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprint("code ", e.code)
+}
+
+func TestAsJoinedErrors(t *testing.T) {
+	var ce *codeError
+	joined := errors.Join(errors.New("a"), &codeError{code: 7})
+	assert.True(t, chain.As(chain.Build(joined, "x"), &ce))
+	assert.Equals(t, 7, ce.code)
+}
+
 func TestBuild(t *testing.T) {
 	assert.Panics(t, "chain: Build called with zero arguments", func() {
 		chain.Build()
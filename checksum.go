@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+)
+
+// BuildChecksummed is like Build, but also returns a checksum of the chain's
+// values, which can later be checked with Verify to detect corruption or
+// unexpected changes.
+//
+// The checksum is a 64-bit FNV-1a hash over each value in the chain, from
+// outermost to innermost, as its type name followed by its JSON encoding.
+// Values that cannot be encoded as JSON fall back to their %#v formatting,
+// which for pointers includes addresses that are not stable across processes.
+func BuildChecksummed(vals ...interface{}) (interface{}, uint64) {
+	v := Build(vals...)
+	return v, checksum(v)
+}
+
+// Verify reports whether the checksum of the values in v's chain, computed as
+// described by BuildChecksummed, equals sum.
+func Verify(v interface{}, sum uint64) bool {
+	return checksum(v) == sum
+}
+
+func checksum(v interface{}) uint64 {
+	h := fnv.New64a()
+	walk(v, func(e interface{}) bool {
+		fmt.Fprintf(h, "%v\x00", reflect.TypeOf(e))
+		if b, err := json.Marshal(e); err == nil {
+			h.Write(b)
+		} else {
+			fmt.Fprintf(h, "%#v", e)
+		}
+		h.Write([]byte{0})
+		return true
+	})
+	return h.Sum64()
+}
@@ -0,0 +1,27 @@
+package chain_test
+
+import (
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestBuildChecksummed(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+
+	ch, sum := chain.BuildChecksummed("a", 1, point{1, 2})
+	assert.True(t, chain.Verify(ch, sum))
+	assert.True(t, chain.Verify(chain.Build("a", 1, point{1, 2}), sum))
+	assert.False(t, chain.Verify(chain.Build("a", 1, point{2, 1}), sum))
+	assert.False(t, chain.Verify(chain.Build("a", 1.0, point{1, 2}), sum))
+	assert.False(t, chain.Verify(chain.Build(1, "a", point{1, 2}), sum))
+	assert.False(t, chain.Verify(chain.Build("a", 1), sum))
+
+	// values that can't be encoded as JSON still contribute
+	ch, sum = chain.BuildChecksummed("a", make(chan int))
+	assert.True(t, chain.Verify(ch, sum))
+	assert.False(t, chain.Verify(chain.Build("a", make(chan int)), sum))
+}
@@ -0,0 +1,47 @@
+package chain
+
+// CompiledChain is a snapshot of a chain's values, indexed so that repeated
+// membership queries are cheaper than calling Is on the chain each time.
+//
+// Changes made to the chain after it is compiled are not reflected.
+type CompiledChain struct {
+	set    map[interface{}]struct{}
+	others []interface{}
+}
+
+// CompileChain collects the values in v's chain into a CompiledChain.
+func CompileChain(v interface{}) *CompiledChain {
+	c := &CompiledChain{set: make(map[interface{}]struct{})}
+	walk(v, func(e interface{}) bool {
+		if basic(e) {
+			c.set[e] = struct{}{}
+		} else {
+			c.others = append(c.others, e)
+		}
+		return true
+	})
+	return c
+}
+
+// Contains reports whether any value in the compiled chain matches target,
+// using the rules described by Is.
+//
+// Values of basic kinds (booleans, numbers and strings) are held in a set, so
+// a basic target that is in the set is found without a walk, and one that is
+// not need only be compared against the chain's other values. Those values,
+// which may have Is methods or be incomparable, are always compared one by
+// one, so no answer is ever a false positive.
+func (c *CompiledChain) Contains(target interface{}) bool {
+	if basic(target) {
+		if _, ok := c.set[target]; ok {
+			return true
+		}
+	}
+
+	for _, e := range c.others {
+		if match(e, target) {
+			return true
+		}
+	}
+	return false
+}
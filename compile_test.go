@@ -0,0 +1,44 @@
+package chain_test
+
+import (
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestCompileChain(t *testing.T) {
+	c := chain.CompileChain(chain.Build("a", 1, []int{2}, &isMatcher{to: "z"}, nil))
+	assert.True(t, c.Contains("a"))
+	assert.True(t, c.Contains(1))
+	assert.True(t, c.Contains([]int{2}))
+	assert.True(t, c.Contains("z"))
+	assert.True(t, c.Contains(nil))
+	assert.False(t, c.Contains("b"))
+	assert.False(t, c.Contains(int64(1)))
+	assert.False(t, c.Contains([]int{3}))
+}
+
+func largeChain() interface{} {
+	vals := make([]interface{}, 10000)
+	for i := range vals {
+		vals[i] = i
+	}
+	return chain.Build(vals...)
+}
+
+func BenchmarkIsLarge(b *testing.B) {
+	ch := largeChain()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chain.Is(ch, i%20000)
+	}
+}
+
+func BenchmarkCompiledChainContains(b *testing.B) {
+	c := chain.CompileChain(largeChain())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Contains(i % 20000)
+	}
+}
@@ -0,0 +1,92 @@
+package chain
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/rbranson/chain/x"
+)
+
+type converter struct {
+	from reflect.Type
+	conv func(interface{}) interface{}
+}
+
+var converters = struct {
+	sync.RWMutex
+	byTarget map[reflect.Type][]converter
+}{
+	byTarget: make(map[reflect.Type][]converter),
+}
+
+// RegisterConverter registers conv as a way of converting values of the type
+// pointed to by fromExample into the type pointed to by toExample, for use by
+// AsConverted. The examples are typically of the form (*T)(nil). Registering a
+// converter for a pair of types that already has one replaces it.
+//
+// RegisterConverter is safe to call concurrently with itself and AsConverted.
+// It panics if either example is not a pointer.
+func RegisterConverter(fromExample, toExample interface{}, conv func(interface{}) interface{}) {
+	from, err := x.MakeTypeExample(fromExample)
+	if err != nil {
+		panic("chain: fromExample " + err.Error())
+	}
+
+	to, err := x.MakeTypeExample(toExample)
+	if err != nil {
+		panic("chain: toExample " + err.Error())
+	}
+
+	converters.Lock()
+	defer converters.Unlock()
+
+	// copy rather than modify in place, since AsConverted reads the slice
+	// without holding the lock.
+	cs := append([]converter(nil), converters.byTarget[to.Type()]...)
+	for i, c := range cs {
+		if c.from == from.Type() {
+			cs[i].conv = conv
+			converters.byTarget[to.Type()] = cs
+			return
+		}
+	}
+	converters.byTarget[to.Type()] = append(cs, converter{from: from.Type(), conv: conv})
+}
+
+// AsConverted finds a value in v's chain that is, or can be converted into, a
+// T. A value matching T using the rules described by As takes precedence.
+// Otherwise, the first value in the chain that is assignable to the source
+// type of a converter registered for T with RegisterConverter is converted.
+// If several converters apply to a value, the first registered is used.
+// Conversion results that are not of type T are ignored.
+func AsConverted[T any](v interface{}) (T, bool) {
+	var t T
+	if As(v, &t) {
+		return t, true
+	}
+
+	converters.RLock()
+	cs := converters.byTarget[reflect.TypeOf(&t).Elem()]
+	converters.RUnlock()
+	if len(cs) == 0 {
+		return t, false
+	}
+
+	found := false
+	walk(v, func(e interface{}) bool {
+		if e == nil {
+			return true
+		}
+
+		for _, c := range cs {
+			if reflect.TypeOf(e).AssignableTo(c.from) {
+				t, found = c.conv(e).(T)
+				if found {
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return t, found
+}
@@ -0,0 +1,53 @@
+package chain_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+type stamp string
+
+func TestAsConverted(t *testing.T) {
+	assert.Panics(t, "chain: fromExample must be a pointer", func() {
+		chain.RegisterConverter(time.Time{}, (*stamp)(nil), nil)
+	})
+
+	_, ok := chain.AsConverted[stamp](chain.Build(1, time.Time{}))
+	assert.False(t, ok)
+
+	chain.RegisterConverter((*time.Time)(nil), (*stamp)(nil), func(v interface{}) interface{} {
+		return stamp(v.(time.Time).Format(time.RFC3339))
+	})
+	chain.RegisterConverter((*int)(nil), (*stamp)(nil), func(v interface{}) interface{} {
+		return stamp(strconv.Itoa(v.(int)))
+	})
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	s, ok := chain.AsConverted[stamp](chain.Build(1, ts))
+	assert.True(t, ok)
+	assert.Equals(t, stamp("2020-01-02T03:04:05Z"), s)
+
+	s, ok = chain.AsConverted[stamp](chain.Build(ts, 1))
+	assert.True(t, ok)
+	assert.Equals(t, stamp("1"), s)
+
+	// direct matches take precedence
+	s, ok = chain.AsConverted[stamp](chain.Build(stamp("direct"), 1))
+	assert.True(t, ok)
+	assert.Equals(t, stamp("direct"), s)
+
+	// re-registering replaces the converter
+	chain.RegisterConverter((*int)(nil), (*stamp)(nil), func(v interface{}) interface{} {
+		return stamp("int")
+	})
+	s, ok = chain.AsConverted[stamp](7)
+	assert.True(t, ok)
+	assert.Equals(t, stamp("int"), s)
+
+	_, ok = chain.AsConverted[stamp]("x")
+	assert.False(t, ok)
+}
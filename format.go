@@ -0,0 +1,121 @@
+package chain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxFormatDepth is the number of values printed when formatting a chain,
+// beyond which the rest of the chain is elided.
+const maxFormatDepth = 100
+
+// Format implements fmt.Formatter for chains built by Build.
+//
+// The %v and %s verbs print the values in the chain from outermost to
+// innermost, joined by arrows, such as "c -> b -> a". With the + flag, as in
+// %+v, each value is printed on its own line, indented one level deeper than
+// the value wrapping it. In both cases, only the first 100 values are
+// printed, followed by "..." if the chain is longer. Any other verb is
+// applied to the outermost value alone.
+func (l *buildLink) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'v', 's':
+		formatChain(f, l, f.Flag('+'), nil)
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, verb), l.v)
+	}
+}
+
+// SafeFormat returns the values in v's chain formatted as by the %v verb of a
+// chain built by Build, for any chain. Nodes are tracked by identity, so that
+// if the chain loops back to a node that has already been printed, the marker
+// "<cycle>" is printed in its place and formatting stops.
+func SafeFormat(v interface{}) string {
+	var sb strings.Builder
+	formatChain(&sb, v, false, make(map[identity]struct{}))
+	return sb.String()
+}
+
+// formatChain writes the values in v's chain to w. If seen is not nil, it is
+// used to detect cycles.
+func formatChain(w io.Writer, v interface{}, multiline bool, seen map[identity]struct{}) {
+	for depth := 0; ; depth++ {
+		if depth > 0 {
+			if multiline {
+				io.WriteString(w, "\n"+strings.Repeat("  ", depth))
+			} else {
+				io.WriteString(w, " -> ")
+			}
+		}
+
+		if depth == maxFormatDepth {
+			io.WriteString(w, "...")
+			return
+		}
+
+		if id, ok := identify(v); ok && seen != nil {
+			if _, ok := seen[id]; ok {
+				io.WriteString(w, "<cycle>")
+				return
+			}
+			seen[id] = struct{}{}
+		}
+
+		fmt.Fprint(w, elem(v))
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return
+		}
+	}
+}
+
+// DOTOptions configures the output of ToDOT. The zero value uses the
+// defaults described for each field.
+type DOTOptions struct {
+	// Name is the name of the graph. By default, it is "chain".
+	Name string
+
+	// Label, if set, returns the label of the node for each element. By
+	// default, the label is the element's type name, followed on a second
+	// line by the result of its String method if it implements fmt.Stringer.
+	Label func(v interface{}) string
+}
+
+// ToDOT returns a Graphviz description of v's chain, in the DOT language, with
+// a node for each node of the chain and an edge from each node to each value
+// it wraps. The graph has the topology returned by Graph.
+func ToDOT(v interface{}, opts DOTOptions) string {
+	name := opts.Name
+	if name == "" {
+		name = "chain"
+	}
+	label := opts.Label
+	if label == nil {
+		label = dotLabel
+	}
+
+	g := Graph(v)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %s {\n", strconv.Quote(name))
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&sb, "\tn%d [label=%s];\n", n.ID, strconv.Quote(label(n.Value)))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "\tn%d -> n%d;\n", e.From, e.To)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotLabel returns the default label used by ToDOT for v.
+func dotLabel(v interface{}) string {
+	label := fmt.Sprintf("%T", v)
+	if s, ok := v.(fmt.Stringer); ok {
+		label += "\n" + s.String()
+	}
+	return label
+}
@@ -0,0 +1,63 @@
+package chain_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestFormat(t *testing.T) {
+	ch := chain.Build("a", 1, "c")
+	assert.Equals(t, "c -> 1 -> a", fmt.Sprintf("%v", ch))
+	assert.Equals(t, "c -> 1 -> a", fmt.Sprintf("%s", ch))
+	assert.Equals(t, "c\n  1\n    a", fmt.Sprintf("%+v", ch))
+	assert.Equals(t, `"c"`, fmt.Sprintf("%q", ch))
+
+	vals := make([]interface{}, 150)
+	for i := range vals {
+		vals[i] = i
+	}
+	s := fmt.Sprint(chain.Build(vals...))
+	assert.True(t, strings.HasPrefix(s, "149 -> 148 -> "))
+	assert.True(t, strings.HasSuffix(s, " -> 50 -> ..."))
+}
+
+func TestSafeFormat(t *testing.T) {
+	assert.Equals(t, "c -> 1 -> a", chain.SafeFormat(chain.Build("a", 1, "c")))
+	assert.Equals(t, "a", chain.SafeFormat("a"))
+
+	l1 := (&chain.Link{}).Set("x")
+	l2 := (&chain.Link{}).Set("y")
+	l1.Wrap(l2)
+	l2.Wrap(l1)
+	s := chain.SafeFormat(chain.Build(l1, "z"))
+	assert.True(t, strings.HasPrefix(s, "z -> "))
+	assert.True(t, strings.HasSuffix(s, " -> <cycle>"))
+	assert.Equals(t, 3, strings.Count(s, "->"))
+}
+
+func TestToDOT(t *testing.T) {
+	v := chain.Build(time.Second, "a")
+	want := `digraph "chain" {
+	n0 [label="string"];
+	n1 [label="time.Duration\n1s"];
+	n0 -> n1;
+}
+`
+	assert.Equals(t, want, chain.ToDOT(v, chain.DOTOptions{}))
+
+	j := chain.Join("a", "b")
+	got := chain.ToDOT(j, chain.DOTOptions{
+		Name: "joined",
+		Label: func(v interface{}) string {
+			return fmt.Sprint(v)
+		},
+	})
+	assert.True(t, strings.HasPrefix(got, `digraph "joined" {`))
+	assert.True(t, strings.Contains(got, "\tn1 [label=\"a\"];\n\tn2 [label=\"b\"];\n"))
+	assert.True(t, strings.Contains(got, "\tn0 -> n1;\n\tn0 -> n2;\n"))
+}
@@ -1,7 +1,7 @@
 module github.com/rbranson/chain
 
-go 1.14
+go 1.23
 
-require (
-	github.com/google/go-cmp v0.5.0
-)
+require github.com/google/go-cmp v0.5.0
+
+require golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
@@ -0,0 +1,137 @@
+package chain
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Topology describes the shape of a chain as a directed graph, for use by
+// visualization and analysis tools.
+type Topology struct {
+	// Nodes holds a node for each distinct node of the chain, in the order
+	// described by Walk, so Nodes[0] is the chain itself.
+	Nodes []Node
+
+	// Edges holds an edge from each node to each value it wraps. The edges
+	// from a node are in the order its children are walked.
+	Edges []Edge
+}
+
+// Node is a node of a Topology.
+type Node struct {
+	// ID identifies the node within its Topology. It is the node's index in
+	// Nodes.
+	ID int
+
+	// Value is the element the node represents, as described by Walk.
+	Value interface{}
+
+	// Type is the name of Value's concrete type, as formatted by %T.
+	Type string
+}
+
+// Edge is an edge of a Topology, from a node to a node it wraps.
+type Edge struct {
+	From, To int
+}
+
+// Graph returns the topology of v's chain, including every branch. Nodes that
+// are reached more than once, compared by identity, appear once in the
+// result and are not descended into again, so shared and cyclic chains are
+// represented faithfully and Graph terminates.
+func Graph(v interface{}) Topology {
+	g := &grapher{ids: make(map[identity]int)}
+	g.visit(v)
+	return g.t
+}
+
+type grapher struct {
+	t   Topology
+	ids map[identity]int
+}
+
+// visit adds the node v and everything it wraps, returning its ID.
+func (g *grapher) visit(v interface{}) int {
+	id, identified := identify(v)
+	if identified {
+		if n, ok := g.ids[id]; ok {
+			return n
+		}
+	}
+
+	e := elem(v)
+	n := len(g.t.Nodes)
+	g.t.Nodes = append(g.t.Nodes, Node{ID: n, Value: e, Type: fmt.Sprintf("%T", e)})
+	if identified {
+		g.ids[id] = n
+	}
+
+	children := append([]interface{}(nil), branches(e)...)
+	if next, ok := Unwrap(v); ok {
+		children = append(children, next)
+	}
+	for _, c := range children {
+		g.t.Edges = append(g.t.Edges, Edge{From: n, To: g.visit(c)})
+	}
+	return n
+}
+
+// TreeStats summarizes the shape of a chain, as returned by Stats.
+type TreeStats struct {
+	// Nodes is the number of distinct nodes in the chain, including every
+	// branch.
+	Nodes int
+
+	// MaxDepth is the greatest distance from v to any node, counting the
+	// values wrapped along the shortest path to it, so a chain of one value
+	// has a MaxDepth of 0.
+	MaxDepth int
+
+	// MaxBranching is the greatest number of values wrapped by a single
+	// node. It is 1 for a chain of several values that does not branch.
+	MaxBranching int
+
+	// Types counts the elements of each concrete type. The count for nil
+	// elements is held under a nil key.
+	Types map[reflect.Type]int
+}
+
+// Stats returns statistics about the shape of v's chain, including every
+// branch. Like Graph, it counts nodes reached more than once only once, so it
+// terminates for shared and cyclic chains.
+func Stats(v interface{}) TreeStats {
+	g := Graph(v)
+	st := TreeStats{
+		Nodes: len(g.Nodes),
+		Types: make(map[reflect.Type]int),
+	}
+	for _, n := range g.Nodes {
+		st.Types[reflect.TypeOf(n.Value)]++
+	}
+
+	out := make([][]int, len(g.Nodes))
+	for _, e := range g.Edges {
+		out[e.From] = append(out[e.From], e.To)
+	}
+
+	depth := make([]int, len(g.Nodes))
+	for i := range depth {
+		depth[i] = -1
+	}
+	depth[0] = 0
+	queue := []int{0}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		st.MaxDepth = max(st.MaxDepth, depth[n])
+		st.MaxBranching = max(st.MaxBranching, len(out[n]))
+		for _, c := range out[n] {
+			if depth[c] < 0 {
+				depth[c] = depth[n] + 1
+				queue = append(queue, c)
+			}
+		}
+	}
+	return st
+}
@@ -0,0 +1,76 @@
+package chain_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestGraph(t *testing.T) {
+	j := chain.Join(chain.Build("a", 1), "b")
+	g := chain.Graph(chain.Build("z", j))
+
+	assert.Equals(t, []chain.Node{
+		{ID: 0, Value: j, Type: "*chain.joined"},
+		{ID: 1, Value: 1, Type: "int"},
+		{ID: 2, Value: "a", Type: "string"},
+		{ID: 3, Value: "b", Type: "string"},
+		{ID: 4, Value: "z", Type: "string"},
+	}, g.Nodes)
+	assert.Equals(t, []chain.Edge{
+		{From: 1, To: 2},
+		{From: 0, To: 1},
+		{From: 0, To: 3},
+		{From: 0, To: 4},
+	}, g.Edges)
+
+	assert.Equals(t, chain.Topology{
+		Nodes: []chain.Node{{ID: 0, Value: "a", Type: "string"}},
+	}, chain.Graph("a"))
+}
+
+func TestGraphErrors(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	joined := errors.Join(errA, errB)
+	g := chain.Graph(joined)
+
+	assert.Equals(t, 3, len(g.Nodes))
+	assert.Equals(t, []chain.Edge{
+		{From: 0, To: 1},
+		{From: 0, To: 2},
+	}, g.Edges)
+	assert.True(t, g.Nodes[2].Value == errB)
+}
+
+func TestGraphCycle(t *testing.T) {
+	l1 := (&chain.Link{}).Set("1")
+	l2 := (&chain.Link{}).Set("2")
+	l1.Wrap(l2)
+	l2.Wrap(l1)
+
+	g := chain.Graph(l1)
+	assert.Equals(t, 2, len(g.Nodes))
+	assert.Equals(t, []chain.Edge{{From: 1, To: 0}, {From: 0, To: 1}}, g.Edges)
+}
+
+func TestStats(t *testing.T) {
+	j := chain.Join(chain.Build("a", 1), "b", chain.Build(2, 3, 4))
+	st := chain.Stats(chain.Build("z", j))
+
+	assert.Equals(t, 8, st.Nodes)
+	assert.Equals(t, 3, st.MaxDepth)
+	assert.Equals(t, 4, st.MaxBranching)
+	assert.Equals(t, map[reflect.Type]int{
+		reflect.TypeOf(j):  1,
+		reflect.TypeOf(""): 3,
+		reflect.TypeOf(0):  4,
+	}, st.Types)
+
+	st = chain.Stats("a")
+	assert.Equals(t, 1, st.Nodes)
+	assert.Equals(t, 0, st.MaxDepth)
+	assert.Equals(t, 0, st.MaxBranching)
+}
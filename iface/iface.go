@@ -15,3 +15,11 @@ type As interface {
 type Wrap interface {
 	Wrap(v interface{}) bool
 }
+
+type UnwrapErrors interface {
+	Unwrap() []error
+}
+
+type UnwrapMulti interface {
+	Unwrap() []interface{}
+}
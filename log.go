@@ -0,0 +1,76 @@
+package chain
+
+import "sync"
+
+// Log is an append-only log of values built on a chain, where the most
+// recently appended value is the outermost. The zero value is an empty Log.
+//
+// A Log is not safe for concurrent use. Use SyncLog, or provide external
+// locking, if it is shared between goroutines.
+type Log struct {
+	head Holder
+}
+
+// Append adds v to the log as its new outermost value, using the rules
+// described by Build to chain it to the previous head. It takes constant time,
+// and the existing history is preserved.
+func (l *Log) Append(v interface{}) {
+	head, ok := l.head.Get()
+	if !ok {
+		l.head.Set(v)
+		return
+	}
+	l.head.Set(Build(head, v))
+}
+
+// Head returns the chain holding the log's values, and false if the log is
+// empty.
+func (l *Log) Head() (interface{}, bool) {
+	return l.head.Get()
+}
+
+// Recent returns up to n of the most recently appended values, newest first.
+func (l *Log) Recent(n int) []interface{} {
+	head, ok := l.head.Get()
+	if !ok || n <= 0 {
+		return nil
+	}
+
+	var vals []interface{}
+	walk(head, func(e interface{}) bool {
+		vals = append(vals, e)
+		return len(vals) < n
+	})
+	return vals
+}
+
+// SyncLog is a Log that is safe for concurrent use. The zero value is an
+// empty SyncLog.
+type SyncLog struct {
+	mu  sync.RWMutex
+	log Log
+}
+
+// Append adds v to the log as its new outermost value, as Log.Append does.
+func (l *SyncLog) Append(v interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.log.Append(v)
+}
+
+// Head returns the chain holding the log's values, as Log.Head does. The
+// chain itself is never modified by later appends, so it may be used without
+// further locking.
+func (l *SyncLog) Head() (interface{}, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.log.Head()
+}
+
+// Recent returns up to n of the most recently appended values, as Log.Recent
+// does.
+func (l *SyncLog) Recent(n int) []interface{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.log.Recent(n)
+}
@@ -0,0 +1,51 @@
+package chain_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestLog(t *testing.T) {
+	var l chain.Log
+	_, ok := l.Head()
+	assert.False(t, ok)
+	assert.Equals(t, 0, len(l.Recent(3)))
+
+	l.Append("a")
+	l.Append("b")
+	head, ok := l.Head()
+	assert.True(t, ok)
+
+	l.Append("c")
+	assert.Equals(t, []interface{}{"c", "b"}, l.Recent(2))
+	assert.Equals(t, []interface{}{"c", "b", "a"}, l.Recent(5))
+	assert.Equals(t, 0, len(l.Recent(0)))
+	assert.Equals(t, []interface{}{"c", "b", "a"}, l.Recent(math.MaxInt))
+
+	// earlier heads are unaffected by appends
+	assert.False(t, chain.Is(head, "c"))
+	assert.True(t, chain.Is(head, "a"))
+}
+
+func TestSyncLog(t *testing.T) {
+	var l chain.SyncLog
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.Append(i)
+			l.Recent(3)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equals(t, 10, len(l.Recent(20)))
+	head, ok := l.Head()
+	assert.True(t, ok)
+	assert.True(t, chain.Is(head, 0))
+}
@@ -0,0 +1,525 @@
+package chain
+
+import (
+	"cmp"
+	"math"
+	"reflect"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"github.com/rbranson/chain/iface"
+	"github.com/rbranson/chain/x"
+)
+
+// EqualUnordered reports whether the chains a and b contain the same values,
+// regardless of their order. Each value in a must match a distinct value in
+// b, using the rules described by Is.
+//
+// Values of basic kinds (booleans, numbers and strings) are counted using a
+// map. Any other values are matched pairwise, which is O(n²) in the worst
+// case.
+func EqualUnordered(a, b interface{}) bool {
+	as, bs := collect(a), collect(b)
+	if len(as) != len(bs) {
+		return false
+	}
+
+	counts := make(map[interface{}]int)
+	var rest []interface{}
+	for _, e := range as {
+		if basic(e) {
+			counts[e]++
+		} else {
+			rest = append(rest, e)
+		}
+	}
+
+	var others []interface{}
+	for _, e := range bs {
+		if basic(e) && counts[e] > 0 {
+			counts[e]--
+		} else {
+			others = append(others, e)
+		}
+	}
+
+	for e, n := range counts {
+		for ; n > 0; n-- {
+			rest = append(rest, e)
+		}
+	}
+
+	used := make([]bool, len(others))
+next:
+	for _, e := range rest {
+		for i, o := range others {
+			if !used[i] && match(e, o) {
+				used[i] = true
+				continue next
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// basic reports whether v is of a basic kind that does not implement iface.Is,
+// so that == agrees with the rules described by Is.
+func basic(v interface{}) bool {
+	if _, ok := v.(iface.Is); ok || v == nil {
+		return false
+	}
+
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// IsBy reports whether any value in v's chain has a method named methodName
+// whose result matches target, using the rules described by Is. The method
+// must take no arguments and return at least one value, of which only the
+// first is considered. Values without such a method are skipped.
+//
+// The method is looked up and called using reflection for every value in the
+// chain, which is considerably slower than implementing an Is method.
+func IsBy(v interface{}, methodName string, target interface{}) bool {
+	found := false
+	walk(v, func(e interface{}) bool {
+		rv, ok := x.ValueOf(e)
+		if !ok {
+			return true
+		}
+
+		m := rv.MethodByName(methodName)
+		if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() == 0 {
+			return true
+		}
+
+		found = match(m.Call(nil)[0].Interface(), target)
+		return !found
+	})
+	return found
+}
+
+// IsInRange reports whether any value in v's chain is of type T and falls
+// within the inclusive range [lo, hi]. Values of other types are skipped.
+//
+// T is constrained by cmp.Ordered, the standard library's successor to
+// constraints.Ordered, so any type supporting < and > can be used.
+func IsInRange[T cmp.Ordered](v interface{}, lo, hi T) bool {
+	found := false
+	walk(v, func(e interface{}) bool {
+		if t, ok := e.(T); ok {
+			found = t >= lo && t <= hi
+		}
+		return !found
+	})
+	return found
+}
+
+// Score returns a weighted count of the values in v's chain that satisfy
+// pred, where a match at depth d contributes decay^d. The depth of v itself
+// is 0, so outer matches count more than inner ones. For example, with a
+// decay of 0.5, matches at depths 0 and 2 score 1 + 0.25 = 1.25.
+//
+// A decay of 1 counts every match equally, and a decay of 0 counts only a
+// match of v itself. Score returns 0 if v is nil or nothing matches.
+//
+// In a branching chain, the depth of an element is the number of values
+// wrapped along the path from v to it, so the children of an element at
+// depth d are at depth d+1, like the value it wraps.
+//
+// Score panics if decay is not within [0, 1].
+func Score(v interface{}, pred func(interface{}) bool, decay float64) float64 {
+	if decay < 0 || decay > 1 {
+		panic("chain: decay must be within [0, 1]")
+	}
+
+	if v == nil {
+		return 0
+	}
+
+	score := 0.0
+	walkDepth(v, func(depth int, e interface{}) bool {
+		if pred(e) {
+			score += math.Pow(decay, float64(depth))
+		}
+		return true
+	})
+	return score
+}
+
+// IsNormalized reports whether any value in v's chain matches target after
+// both have been passed through norm, using the rules described by Is. It can
+// be used to compare values after trimming or case folding, for example.
+//
+// norm is called for target and then again for every value compared, so it
+// should be cheap.
+func IsNormalized(v, target interface{}, norm func(interface{}) interface{}) bool {
+	target = norm(target)
+
+	found := false
+	walk(v, func(e interface{}) bool {
+		found = match(norm(e), target)
+		return !found
+	})
+	return found
+}
+
+// IsSubsetOf reports whether every value in v's chain matches some value in
+// other's chain, as Is(other, value) would.
+//
+// other's chain is collected once. Values of basic kinds (booleans, numbers
+// and strings) are looked up using a map, but any other values must be
+// compared against every value in other, which is O(n·m) in the worst case.
+func IsSubsetOf(v interface{}, other interface{}) bool {
+	set := make(map[interface{}]struct{})
+	var rest []interface{}
+	for _, o := range collect(other) {
+		if basic(o) {
+			set[o] = struct{}{}
+		} else {
+			rest = append(rest, o)
+		}
+	}
+
+	subset := true
+	walk(v, func(e interface{}) bool {
+		if basic(e) {
+			if _, ok := set[e]; ok {
+				return true
+			}
+		}
+
+		subset = false
+		for _, o := range rest {
+			if match(o, e) {
+				subset = true
+				break
+			}
+		}
+		return subset
+	})
+	return subset
+}
+
+// IsLike reports whether any value in v's chain is of the same type as
+// prototype and has the same values for each of prototype's exported fields
+// that are not the zero value. Zero fields act as wildcards, so a field cannot
+// be required to be zero: it matches anything. Unexported fields are ignored.
+// Fields are compared using reflect.DeepEqual.
+//
+// IsLike panics if prototype is not a struct or a non-nil pointer to one.
+func IsLike(v interface{}, prototype interface{}) bool {
+	pv, ok := x.ValueOf(prototype)
+	if ok && pv.Kind() == reflect.Ptr {
+		pv = pv.Elem()
+	}
+	if !ok || pv.Kind() != reflect.Struct {
+		panic("chain: prototype must be a struct or a pointer to a struct")
+	}
+
+	pt := reflect.TypeOf(prototype)
+	found := false
+	walk(v, func(e interface{}) bool {
+		ev, ok := x.ValueOf(e)
+		if !ok || ev.Type() != pt {
+			return true
+		}
+		if ev.Kind() == reflect.Ptr {
+			ev = ev.Elem()
+		}
+
+		found = true
+		for i := 0; i < pv.NumField(); i++ {
+			f := pv.Field(i)
+			if !pv.Type().Field(i).IsExported() || f.IsZero() {
+				continue
+			}
+			if !reflect.DeepEqual(f.Interface(), ev.Field(i).Interface()) {
+				found = false
+				break
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// IsFuncDepth reports whether pred returns true for any value in v's chain,
+// stopping at the first that does. Along with each value, pred is passed its
+// depth: the number of times Unwrap was called to reach it, so v itself has
+// a depth of 0. In a branching chain, the children of an element at depth d
+// are at depth d+1, like the value it wraps.
+func IsFuncDepth(v interface{}, pred func(depth int, v interface{}) bool) bool {
+	found := false
+	walkDepth(v, func(depth int, e interface{}) bool {
+		found = pred(depth, e)
+		return !found
+	})
+	return found
+}
+
+// maxSimilarLen is the length, in runes, beyond which IsSimilar does not
+// compare strings.
+const maxSimilarLen = 1024
+
+// IsSimilar reports whether any string in v's chain is within maxDistance
+// edits of s, where an edit is the insertion, deletion or substitution of a
+// rune (the Levenshtein distance). Values that are not strings are skipped.
+//
+// Each comparison costs O(n·m) time for strings of n and m runes, although
+// it stops early once the distance is known to exceed maxDistance. To bound
+// the cost, strings longer than 1024 runes are never considered similar.
+func IsSimilar(v interface{}, s string, maxDistance int) bool {
+	target := []rune(s)
+	if len(target) > maxSimilarLen {
+		return false
+	}
+
+	found := false
+	walk(v, func(e interface{}) bool {
+		if es, ok := e.(string); ok {
+			found = withinDistance([]rune(es), target, maxDistance)
+		}
+		return !found
+	})
+	return found
+}
+
+// withinDistance reports whether the Levenshtein distance between a and b is
+// at most max.
+func withinDistance(a, b []rune, max int) bool {
+	if len(a) > maxSimilarLen || len(a)-len(b) > max || len(b)-len(a) > max {
+		return false
+	}
+
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return false
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)] <= max
+}
+
+// WalkStats describes the work done by IsStats.
+type WalkStats struct {
+	// Steps is the number of values visited, including those in every
+	// branch searched, as described by Is.
+	Steps int
+
+	// Comparisons is the number of values compared with the target using
+	// reflect.DeepEqual.
+	Comparisons int
+
+	// IsMethodCalled reports whether the Is method of any value was called.
+	IsMethodCalled bool
+}
+
+// IsStats is like Is, but also reports statistics about the work done to
+// determine the result, which is the same as Is would return.
+func IsStats(v, target interface{}) (bool, WalkStats) {
+	var st WalkStats
+	found := is(v, target, &st)
+	return found, st
+}
+
+// IsAmong is like Is, but only compares target against values in v's chain
+// whose types are assignable to one of the types pointed to by typeExamples,
+// which are typically of the form (*T)(nil). Values of other types are
+// skipped without being compared, which avoids needless reflect.DeepEqual
+// calls in chains of mixed types. Assignability is checked using
+// x.TypeExample, as BuildOptions.AllowedTypes does.
+//
+// IsAmong panics if an example is not a pointer.
+func IsAmong(v interface{}, target interface{}, typeExamples ...interface{}) bool {
+	exs := make([]x.TypeExample, len(typeExamples))
+	for i, example := range typeExamples {
+		ex, err := x.MakeTypeExample(example)
+		if err != nil {
+			panic("chain: example " + err.Error())
+		}
+		exs[i] = ex
+	}
+
+	found := false
+	walk(v, func(e interface{}) bool {
+		found = allowed(exs, e) && match(e, target)
+		return !found
+	})
+	return found
+}
+
+// IsNot reports whether no value in v's chain matches target. It is
+// equivalent to !Is(v, target).
+func IsNot(v interface{}, target interface{}) bool {
+	return !Is(v, target)
+}
+
+// IsAny reports whether any value in v's chain matches any of targets, using
+// the rules described by Is. The chain is walked only once, however many
+// targets there are, and the walk stops at the first match.
+func IsAny(v interface{}, targets ...interface{}) bool {
+	if len(targets) == 0 {
+		return false
+	}
+	need := 1
+	return isEach(v, targets, make([]bool, len(targets)), &need)
+}
+
+// IsAll reports whether every one of targets is matched by some value in v's
+// chain, using the rules described by Is. The chain is walked only once,
+// however many targets there are, and the walk stops once every target has
+// been matched. If targets is empty, IsAll returns true.
+func IsAll(v interface{}, targets ...interface{}) bool {
+	if len(targets) == 0 {
+		return true
+	}
+	need := len(targets)
+	return isEach(v, targets, make([]bool, len(targets)), &need)
+}
+
+// isEach walks v's chain as Is does, marking in found the targets matched,
+// and reports whether need more targets were matched before the walk ended.
+// need is decremented for each target matched.
+func isEach(v interface{}, targets []interface{}, found []bool, need *int) bool {
+	for {
+		for i, t := range targets {
+			if !found[i] && match(v, t) {
+				found[i] = true
+				if *need--; *need == 0 {
+					return true
+				}
+			}
+		}
+
+		for _, b := range branches(elem(v)) {
+			if isEach(b, targets, found, need) {
+				return true
+			}
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return false
+		}
+	}
+}
+
+// NoneMatch reports whether no value in v's chain satisfies pred, stopping at
+// the first that does. It is the opposite of Any, so like Any, it treats a
+// nil v as a chain of one nil value, which is passed to pred.
+func NoneMatch(v interface{}, pred func(interface{}) bool) bool {
+	return !Any(v, pred)
+}
+
+// Count returns the number of values in v's chain that match target, using
+// the rules described by Is. A count greater than one often indicates that a
+// value was wrapped more than once.
+func Count(v interface{}, target interface{}) int {
+	n := 0
+	walk(v, func(e interface{}) bool {
+		if match(e, target) {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// Index returns the position in v's chain of the first value that matches
+// target, using the rules described by Is, where 0 is v itself. If no value
+// matches, Index returns -1.
+func Index(v interface{}, target interface{}) int {
+	i, found := 0, false
+	walk(v, func(e interface{}) bool {
+		if match(e, target) {
+			found = true
+			return false
+		}
+		i++
+		return true
+	})
+	if !found {
+		return -1
+	}
+	return i
+}
+
+// Any reports whether some value in v's chain satisfies pred, stopping at the
+// first that does. It is the opposite of NoneMatch. Like Walk and Find, it
+// treats a nil v as a chain of one nil value, which is passed to pred.
+func Any(v interface{}, pred func(interface{}) bool) bool {
+	_, found := Find(v, pred)
+	return found
+}
+
+// Every reports whether every value in v's chain satisfies pred, stopping at
+// the first that does not. Like Any, it treats a nil v as a chain of one nil
+// value.
+func Every(v interface{}, pred func(interface{}) bool) bool {
+	_, found := Find(v, func(e interface{}) bool {
+		return !pred(e)
+	})
+	return !found
+}
+
+// IsEqualSequence reports whether the values in v's chain, from outermost to
+// innermost, equal want. If they differ, it also returns a human-readable
+// diff produced by go-cmp, in which "-" lines are from want and "+" lines are
+// from the chain.
+//
+// Equality is decided by reflect.DeepEqual, consistent with Is. The diff
+// compares unexported fields too, rather than ignoring them, so it reflects
+// the same differences that reflect.DeepEqual sees.
+func IsEqualSequence(v interface{}, want []interface{}) (bool, string) {
+	got := collect(v)
+	if len(got) == len(want) && reflect.DeepEqual(got, want) {
+		return true, ""
+	}
+	return false, gocmp.Diff(want, got, gocmp.Exporter(func(reflect.Type) bool { return true }))
+}
+
+// IsOf is like Is, but compares values of type T with target using ==
+// rather than reflect.DeepEqual, which makes it considerably faster. Values
+// that are not of type T can still match through an Is method, and branching
+// chains are searched as Is does.
+//
+// As with ==, IsOf panics if T is an interface type and a value and target
+// have the same incomparable dynamic type.
+func IsOf[T comparable](v interface{}, target T) bool {
+	found := false
+	walk(v, func(e interface{}) bool {
+		if et, ok := e.(T); ok && et == target {
+			found = true
+		} else if isv, ok := e.(iface.Is); ok && isv.Is(target) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,377 @@
+package chain_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestEqualUnordered(t *testing.T) {
+	assert.True(t, chain.EqualUnordered("a", "a"))
+	assert.True(t, chain.EqualUnordered(chain.Build("a", "b", "c"), chain.Build("c", "a", "b")))
+	assert.True(t, chain.EqualUnordered(chain.Build("a", "a", 1), chain.Build(1, "a", "a")))
+	assert.False(t, chain.EqualUnordered(chain.Build("a", "a", 1), chain.Build(1, 1, "a")))
+	assert.False(t, chain.EqualUnordered(chain.Build("a", "b"), chain.Build("a", "b", "c")))
+
+	// uncomparable values are matched pairwise
+	assert.True(t, chain.EqualUnordered(
+		chain.Build([]int{1}, "a", []int{2}),
+		chain.Build([]int{2}, []int{1}, "a"),
+	))
+	assert.False(t, chain.EqualUnordered(
+		chain.Build([]int{1}, []int{1}),
+		chain.Build([]int{1}, []int{2}),
+	))
+
+	// basic values can be matched by Is methods
+	m := &isMatcher{to: "a"}
+	assert.True(t, chain.EqualUnordered(chain.Build(m, "b"), chain.Build("b", "a")))
+}
+
+type coded struct {
+	code int
+}
+
+func (c coded) Code() int {
+	return c.code
+}
+
+func (c coded) Describe(verbose bool) string {
+	return "coded"
+}
+
+func TestIsBy(t *testing.T) {
+	ch := chain.Build(coded{404}, "x", &coded{500})
+	assert.True(t, chain.IsBy(ch, "Code", 404))
+	assert.True(t, chain.IsBy(ch, "Code", 500))
+	assert.False(t, chain.IsBy(ch, "Code", 200))
+	assert.False(t, chain.IsBy(ch, "Code", "404"))
+
+	// methods that take arguments are skipped
+	assert.False(t, chain.IsBy(ch, "Describe", "coded"))
+	assert.False(t, chain.IsBy(ch, "Missing", nil))
+	assert.False(t, chain.IsBy(nil, "Code", 404))
+}
+
+func TestIsInRange(t *testing.T) {
+	ch := chain.Build(1.5, "b", 10, 20)
+	assert.True(t, chain.IsInRange(ch, 5, 10))
+	assert.True(t, chain.IsInRange(ch, 20, 30))
+	assert.False(t, chain.IsInRange(ch, 11, 19))
+	assert.True(t, chain.IsInRange(ch, 1.0, 2.0))
+	assert.False(t, chain.IsInRange(ch, 2.0, 3.0))
+	assert.True(t, chain.IsInRange(ch, "a", "c"))
+	assert.False(t, chain.IsInRange(ch, int64(0), int64(100)))
+	assert.False(t, chain.IsInRange(nil, 0, 1))
+}
+
+func TestScore(t *testing.T) {
+	assert.Panics(t, "chain: decay must be within [0, 1]", func() {
+		chain.Score("a", nil, 1.5)
+	})
+
+	isString := func(v interface{}) bool {
+		_, ok := v.(string)
+		return ok
+	}
+
+	ch := chain.Build("c", 1, "a")
+	assert.Equals(t, 1.25, chain.Score(ch, isString, 0.5))
+	assert.Equals(t, 2.0, chain.Score(ch, isString, 1))
+	assert.Equals(t, 1.0, chain.Score(ch, isString, 0))
+	assert.Equals(t, 0.0, chain.Score(chain.Build(1, 2), isString, 0.5))
+	assert.Equals(t, 0.0, chain.Score(nil, isString, 0.5))
+
+	tree := chain.Build("d", chain.Join(chain.Build("b", 1), "c"))
+	assert.Equals(t, 0.5+0.25+0.5, chain.Score(tree, isString, 0.5))
+}
+
+func TestIsNormalized(t *testing.T) {
+	trim := func(v interface{}) interface{} {
+		if s, ok := v.(string); ok {
+			return strings.TrimSpace(s)
+		}
+		return v
+	}
+
+	ch := chain.Build(" a", 1, "b \n")
+	assert.True(t, chain.IsNormalized(ch, "a", trim))
+	assert.True(t, chain.IsNormalized(ch, "  b", trim))
+	assert.True(t, chain.IsNormalized(ch, 1, trim))
+	assert.False(t, chain.IsNormalized(ch, "c", trim))
+	assert.False(t, chain.Is(ch, "a"))
+}
+
+func TestIsSubsetOf(t *testing.T) {
+	other := chain.Build("a", 1, []int{2}, &isMatcher{to: "z"}, "b")
+	assert.True(t, chain.IsSubsetOf("a", other))
+	assert.True(t, chain.IsSubsetOf(chain.Build(1, "b", "a"), other))
+	assert.True(t, chain.IsSubsetOf(chain.Build([]int{2}, "z"), other))
+	assert.False(t, chain.IsSubsetOf(chain.Build("a", "c"), other))
+	assert.False(t, chain.IsSubsetOf([]int{3}, other))
+}
+
+type request struct {
+	Method string
+	Path   string
+	Status int
+	Tags   []string
+	secret string
+}
+
+func TestIsLike(t *testing.T) {
+	assert.Panics(t, "chain: prototype must be a struct or a pointer to a struct", func() {
+		chain.IsLike("a", "a")
+	})
+
+	ch := chain.Build(
+		request{Method: "GET", Path: "/a", Status: 200, secret: "x"},
+		&request{Method: "POST", Path: "/b", Tags: []string{"t"}},
+	)
+
+	assert.True(t, chain.IsLike(ch, request{Method: "GET"}))
+	assert.True(t, chain.IsLike(ch, request{Path: "/a", Status: 200}))
+	assert.True(t, chain.IsLike(ch, request{}))
+	assert.True(t, chain.IsLike(ch, request{secret: "y"}))
+	assert.False(t, chain.IsLike(ch, request{Method: "GET", Path: "/b"}))
+	assert.False(t, chain.IsLike(ch, request{Method: "POST"}))
+
+	assert.True(t, chain.IsLike(ch, &request{Method: "POST", Tags: []string{"t"}}))
+	assert.False(t, chain.IsLike(ch, &request{Tags: []string{"u"}}))
+	assert.False(t, chain.IsLike(ch, &request{Method: "GET"}))
+}
+
+func TestIsFuncDepth(t *testing.T) {
+	ch := chain.Build("a", 1, "b", 2)
+	isStringAbove := func(max int) func(int, interface{}) bool {
+		return func(depth int, v interface{}) bool {
+			_, ok := v.(string)
+			return ok && depth < max
+		}
+	}
+
+	assert.False(t, chain.IsFuncDepth(ch, isStringAbove(1)))
+	assert.True(t, chain.IsFuncDepth(ch, isStringAbove(2)))
+
+	var depths []int
+	chain.IsFuncDepth(ch, func(depth int, v interface{}) bool {
+		depths = append(depths, depth)
+		return v == "b"
+	})
+	assert.Equals(t, []int{0, 1}, depths)
+
+	depths = nil
+	tree := chain.Build("d", chain.Join(chain.Build("a", "b"), "c"))
+	chain.IsFuncDepth(tree, func(depth int, v interface{}) bool {
+		depths = append(depths, depth)
+		return false
+	})
+	assert.Equals(t, []int{0, 1, 2, 1, 1}, depths)
+}
+
+func TestIsSimilar(t *testing.T) {
+	ch := chain.Build("kitten", 1, "flaw")
+	assert.True(t, chain.IsSimilar(ch, "kitten", 0))
+	assert.True(t, chain.IsSimilar(ch, "sitting", 3))
+	assert.False(t, chain.IsSimilar(ch, "sitting", 2))
+	assert.True(t, chain.IsSimilar(ch, "lawn", 2))
+	assert.False(t, chain.IsSimilar(ch, "lawn", 1))
+	assert.True(t, chain.IsSimilar(ch, "", 4))
+	assert.True(t, chain.IsSimilar("héllo", "hello", 1))
+
+	long := strings.Repeat("a", 2000)
+	assert.False(t, chain.IsSimilar(long, long, 0))
+}
+
+func TestIsStats(t *testing.T) {
+	ch := chain.Build("a", "b", "c")
+
+	found, st := chain.IsStats(ch, "c")
+	assert.True(t, found)
+	assert.Equals(t, chain.WalkStats{Steps: 1, IsMethodCalled: true}, st)
+
+	found, st = chain.IsStats(ch, "a")
+	assert.True(t, found)
+	assert.Equals(t, chain.WalkStats{Steps: 3, Comparisons: 3, IsMethodCalled: true}, st)
+
+	found, st = chain.IsStats(&unwrappable{wrapped: chain.Hold("x")}, "y")
+	assert.False(t, found)
+	assert.Equals(t, chain.WalkStats{Steps: 2, Comparisons: 2}, st)
+}
+
+func TestIsAmong(t *testing.T) {
+	assert.Panics(t, "chain: example must be a pointer", func() {
+		chain.IsAmong("a", "a", "")
+	})
+
+	m := &isMatcher{to: 1}
+	ch := chain.Build("a", 1, m)
+	assert.True(t, chain.IsAmong(ch, "a", (*string)(nil)))
+	assert.False(t, chain.IsAmong(ch, "a", (*int)(nil)))
+	assert.True(t, chain.IsAmong(ch, 1, (*int)(nil)))
+	assert.False(t, chain.IsAmong(ch, "a"))
+
+	// only the matcher is considered, so the int itself is skipped
+	assert.True(t, chain.IsAmong(chain.Build(2, m), 1, (**isMatcher)(nil)))
+	assert.False(t, chain.IsAmong(chain.Build(1, m), 2, (**isMatcher)(nil)))
+}
+
+func TestIsNot(t *testing.T) {
+	ch := chain.Build("a", "b")
+	assert.False(t, chain.IsNot(ch, "a"))
+	assert.True(t, chain.IsNot(ch, "c"))
+}
+
+func TestNoneMatch(t *testing.T) {
+	isInt := func(v interface{}) bool {
+		_, ok := v.(int)
+		return ok
+	}
+
+	assert.True(t, chain.NoneMatch(chain.Build("a", "b"), isInt))
+	assert.False(t, chain.NoneMatch(chain.Build("a", 1, "b"), isInt))
+	assert.False(t, chain.NoneMatch(nil, func(interface{}) bool { return true }))
+	assert.True(t, chain.NoneMatch(nil, func(interface{}) bool { return false }))
+
+	calls := 0
+	chain.NoneMatch(chain.Build(1, 2, 3), func(v interface{}) bool {
+		calls++
+		return isInt(v)
+	})
+	assert.Equals(t, 1, calls)
+}
+
+func TestIsAnyAll(t *testing.T) {
+	v := chain.Build("a", chain.Join("b", chain.Build("c", "d")), "e")
+
+	assert.True(t, chain.IsAny(v, "x", "c"))
+	assert.False(t, chain.IsAny(v, "x", "y"))
+	assert.False(t, chain.IsAny(v))
+
+	assert.True(t, chain.IsAll(v, "a", "c", "e", "d"))
+	assert.False(t, chain.IsAll(v, "a", "x"))
+	assert.True(t, chain.IsAll(v))
+	assert.True(t, chain.IsAll(v, "a", "a"))
+
+	assert.True(t, chain.IsAll(&isMatcher{to: "m"}, "m"))
+	assert.True(t, chain.IsAny(errors.Join(errors.New("x"), io.EOF), io.ErrClosedPipe, io.EOF))
+}
+
+func TestCount(t *testing.T) {
+	assert.Equals(t, 2, chain.Count(chain.Build("a", "b", "a"), "a"))
+	assert.Equals(t, 0, chain.Count(chain.Build("a", "b"), "c"))
+	assert.Equals(t, 1, chain.Count("a", "a"))
+	assert.Equals(t, 2, chain.Count(chain.Build(&isMatcher{to: "x"}, "y", &isMatcher{to: "x"}), "x"))
+}
+
+func TestIndex(t *testing.T) {
+	v := chain.Build("a", "b", "a", "c")
+	assert.Equals(t, 0, chain.Index(v, "c"))
+	assert.Equals(t, 1, chain.Index(v, "a"))
+	assert.Equals(t, 2, chain.Index(v, "b"))
+	assert.Equals(t, -1, chain.Index(v, "d"))
+	assert.Equals(t, 0, chain.Index("a", "a"))
+}
+
+func TestMatchAgrees(t *testing.T) {
+	m := &isMatcher{to: "foo"}
+	for _, v := range []interface{}{
+		chain.Build("a", m),
+		chain.Build("a", m, "b"),
+		chain.BuildLazy(m, func() interface{} { return "a" }),
+	} {
+		assert.True(t, chain.Is(v, "foo"))
+		assert.Equals(t, 1, chain.Count(v, "foo"))
+		assert.True(t, chain.Index(v, "foo") >= 0)
+		assert.True(t, chain.CompileChain(v).Contains("foo"))
+	}
+
+	v := chain.Build("a", []int{1})
+	assert.True(t, chain.Is(v, []int{1}))
+	assert.Equals(t, 1, chain.Count(v, []int{1}))
+}
+
+func TestAnyEvery(t *testing.T) {
+	isInt := func(v interface{}) bool {
+		_, ok := v.(int)
+		return ok
+	}
+
+	assert.True(t, chain.Any(chain.Build("a", 1, "b"), isInt))
+	assert.False(t, chain.Any(chain.Build("a", "b"), isInt))
+	assert.True(t, chain.Any(nil, func(interface{}) bool { return true }))
+	assert.False(t, chain.Any(nil, func(interface{}) bool { return false }))
+
+	assert.True(t, chain.Every(chain.Build(1, 2, 3), isInt))
+	assert.False(t, chain.Every(chain.Build(1, "a", 3), isInt))
+	assert.False(t, chain.Every(nil, func(interface{}) bool { return false }))
+	assert.True(t, chain.Every(nil, func(v interface{}) bool { return v == nil }))
+
+	for _, pred := range []func(interface{}) bool{
+		func(interface{}) bool { return true },
+		func(interface{}) bool { return false },
+	} {
+		assert.Equals(t, !chain.NoneMatch(nil, pred), chain.Any(nil, pred))
+		assert.Equals(t, !chain.Any(nil, func(v interface{}) bool { return !pred(v) }), chain.Every(nil, pred))
+	}
+
+	calls := 0
+	chain.Every(chain.Build(1, 2, "c"), func(v interface{}) bool {
+		calls++
+		return isInt(v)
+	})
+	assert.Equals(t, 1, calls)
+}
+
+func TestIsEqualSequence(t *testing.T) {
+	ch := chain.Build("a", request{Method: "GET", secret: "x"}, 1)
+
+	ok, diff := chain.IsEqualSequence(ch, []interface{}{1, request{Method: "GET", secret: "x"}, "a"})
+	assert.True(t, ok)
+	assert.Equals(t, "", diff)
+
+	ok, diff = chain.IsEqualSequence(ch, []interface{}{1, request{Method: "GET", secret: "y"}, "a"})
+	assert.False(t, ok)
+	assert.True(t, strings.Contains(diff, `secret: "y"`))
+	assert.True(t, strings.Contains(diff, `secret: "x"`))
+
+	ok, diff = chain.IsEqualSequence(ch, []interface{}{1, "a"})
+	assert.False(t, ok)
+	assert.True(t, diff != "")
+}
+
+func TestIsOf(t *testing.T) {
+	ch := chain.Build("a", 1, "b")
+	assert.True(t, chain.IsOf(ch, "a"))
+	assert.True(t, chain.IsOf(ch, 1))
+	assert.False(t, chain.IsOf(ch, "c"))
+	assert.False(t, chain.IsOf(ch, int64(1)))
+
+	// pointers are compared by identity
+	p := &struct{ A int }{1}
+	assert.True(t, chain.IsOf(chain.Build(p), p))
+	assert.False(t, chain.IsOf(chain.Build(p), &struct{ A int }{1}))
+
+	assert.True(t, chain.IsOf(&isMatcher{to: "z"}, "z"))
+
+	errA := errors.New("a")
+	assert.True(t, chain.IsOf(chain.Build(errors.Join(errors.New("b"), errA), "x"), errA))
+}
+
+func BenchmarkIs(b *testing.B) {
+	ch := chain.Build("a", "b", "c", "d")
+	for i := 0; i < b.N; i++ {
+		chain.Is(ch, "a")
+	}
+}
+
+func BenchmarkIsOf(b *testing.B) {
+	ch := chain.Build("a", "b", "c", "d")
+	for i := 0; i < b.N; i++ {
+		chain.IsOf(ch, "a")
+	}
+}
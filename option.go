@@ -0,0 +1,48 @@
+package chain
+
+// Option is an optional value of type T: either Some value, or None. It
+// formalizes the "value plus filled flag" pattern of Holder. The zero value
+// is None.
+type Option[T any] struct {
+	h TypedHolder[T]
+}
+
+// Some returns an Option holding v.
+func Some[T any](v T) Option[T] {
+	return Option[T]{h: HoldTyped(v)}
+}
+
+// None returns an Option holding no value.
+func None[T any]() Option[T] {
+	return Option[T]{}
+}
+
+// Get returns the Option's value, and false if it is None.
+func (o Option[T]) Get() (T, bool) {
+	return o.h.Get()
+}
+
+// OrElse returns the Option's value, or def if it is None.
+func (o Option[T]) OrElse(def T) T {
+	if v, ok := o.h.Get(); ok {
+		return v
+	}
+	return def
+}
+
+// Map returns Some(fn(v)) if the Option holds v, or None otherwise, in which
+// case fn is not called. Since methods cannot have type parameters, fn must
+// return the same type; use MapOption to map to a different type.
+func (o Option[T]) Map(fn func(T) T) Option[T] {
+	return MapOption(o, fn)
+}
+
+// MapOption returns Some(fn(v)) if o holds v, or None otherwise, in which
+// case fn is not called.
+func MapOption[T, U any](o Option[T], fn func(T) U) Option[U] {
+	v, ok := o.h.Get()
+	if !ok {
+		return None[U]()
+	}
+	return Some(fn(v))
+}
@@ -0,0 +1,37 @@
+package chain_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestOption(t *testing.T) {
+	some := chain.Some(2)
+	v, ok := some.Get()
+	assert.True(t, ok)
+	assert.Equals(t, 2, v)
+	assert.Equals(t, 2, some.OrElse(5))
+
+	none := chain.None[int]()
+	_, ok = none.Get()
+	assert.False(t, ok)
+	assert.Equals(t, 5, none.OrElse(5))
+
+	var zero chain.Option[int]
+	assert.Equals(t, none, zero)
+
+	// Some of a zero value is still Some
+	v, ok = chain.Some(0).Get()
+	assert.True(t, ok)
+	assert.Equals(t, 0, v)
+
+	double := func(v int) int { return v * 2 }
+	assert.Equals(t, chain.Some(4), some.Map(double))
+	assert.Equals(t, none, none.Map(double))
+
+	assert.Equals(t, chain.Some("2"), chain.MapOption(some, strconv.Itoa))
+	assert.Equals(t, chain.None[string](), chain.MapOption(none, strconv.Itoa))
+}
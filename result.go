@@ -0,0 +1,69 @@
+package chain
+
+import "github.com/rbranson/chain/iface"
+
+// Result holds either a successful value of type T or a failure value, such
+// as an error. Like Link, it can wrap another value, its cause, so that
+// results participate in chains the way wrapped errors do.
+type Result[T any] struct {
+	v       TypedHolder[T]
+	failure Holder
+	cause   Holder
+}
+
+// Ok returns a successful Result holding v.
+func Ok[T any](v T) *Result[T] {
+	return &Result[T]{v: HoldTyped(v)}
+}
+
+// Fail returns a failed Result holding failure.
+func Fail[T any](failure interface{}) *Result[T] {
+	return &Result[T]{failure: Hold(failure)}
+}
+
+// Value returns the Result's value, and false if it failed.
+func (r *Result[T]) Value() (T, bool) {
+	return r.v.Get()
+}
+
+// Failure returns the Result's failure value, and false if it succeeded.
+func (r *Result[T]) Failure() (interface{}, bool) {
+	return r.failure.Get()
+}
+
+// Unwrap unwraps the Result's cause
+func (r *Result[T]) Unwrap() (interface{}, bool) {
+	return r.cause.Get()
+}
+
+// Wrap sets the Result's cause
+func (r *Result[T]) Wrap(v interface{}) bool {
+	r.cause.Set(v)
+	return true
+}
+
+// Is returns true if the target matches the Result's failure value, if it
+// failed, or its value otherwise, using the rules described by Is
+func (r *Result[T]) Is(target interface{}) bool {
+	return match(r.held(), target)
+}
+
+// As returns chain.As(v, target) where v is the Result's failure value, if
+// it failed, or its value otherwise
+func (r *Result[T]) As(target interface{}) bool {
+	return As(r.held(), target)
+}
+
+func (r *Result[T]) detached() iface.Wrap {
+	c := *r
+	c.cause = Holder{}
+	return &c
+}
+
+func (r *Result[T]) held() interface{} {
+	if f, ok := r.failure.Get(); ok {
+		return f
+	}
+	v, _ := r.v.Get()
+	return v
+}
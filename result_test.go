@@ -0,0 +1,45 @@
+package chain_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestResult(t *testing.T) {
+	errTimeout := errors.New("timeout")
+
+	failed := chain.Fail[int](errTimeout)
+	_, ok := failed.Value()
+	assert.False(t, ok)
+	f, ok := failed.Failure()
+	assert.True(t, ok)
+	assert.Equals(t, errTimeout, f)
+
+	ok2 := chain.Ok(42)
+	v, ok := ok2.Value()
+	assert.True(t, ok)
+	assert.Equals(t, 42, v)
+	_, ok = ok2.Failure()
+	assert.False(t, ok)
+
+	// a success that recovered from a failure wraps it as its cause
+	ch := chain.Build(failed, ok2)
+	assert.True(t, ch == ok2)
+	assert.True(t, chain.Is(ch, 42))
+	assert.True(t, chain.Is(ch, errTimeout))
+
+	var n int
+	assert.True(t, chain.As(ch, &n))
+	assert.Equals(t, 42, n)
+
+	var err error
+	assert.True(t, chain.As(ch, &err))
+	assert.Equals(t, errTimeout, err)
+
+	cause, ok := chain.Unwrap(ch)
+	assert.True(t, ok)
+	assert.True(t, cause == failed)
+}
@@ -0,0 +1,238 @@
+package chain
+
+import (
+	"reflect"
+
+	"github.com/rbranson/chain/iface"
+)
+
+// Map returns a new chain made of the result of calling fn on each element of
+// v's chain, in the same order. An element that branches, as described by
+// Walk, is not passed to fn, but is replaced by the result of calling Join
+// with its mapped children, so the shape of the chain is preserved.
+//
+// Neither the original chain nor the values returned by fn are modified.
+// Links provided by this package, such as *Link and *Result, are copied and
+// the copies chained in their place, so they no longer reference what the
+// originals wrapped. Other values are held in new links, so a wrapper
+// defined outside this package is not modified either, but is no longer
+// unwrapped: what it wrapped is not part of the new chain.
+func Map(v interface{}, fn func(interface{}) interface{}) interface{} {
+	return rechain(v, func(e interface{}) (interface{}, bool) {
+		return fn(e), true
+	})
+}
+
+// Rebuild returns a new chain made of the result of calling fn on each of the
+// values v's chain was built from, as returned by Unbuild, in the same order.
+//
+// The values returned by fn are chained with the same rules as Build, so a
+// new value implementing iface.Wrap has its Wrap method called, and may be
+// the value Rebuild returns. A value fn returns unchanged is part of the
+// original chain, so it is not modified, but chained as described for Map.
+//
+// Unlike Map, Rebuild does not descend into branches, so fn is passed values
+// such as those returned by Join as a whole.
+func Rebuild(v interface{}, fn func(interface{}) interface{}) interface{} {
+	vals := Unbuild(v)
+	fresh := make([]bool, len(vals))
+	for i, e := range vals {
+		vals[i] = fn(e)
+		fresh[i] = !same(vals[i], e)
+	}
+	return relinkFresh(vals, fresh)
+}
+
+// same reports whether a and b are the same value, as decided by ==. Values
+// that cannot be compared with == are never the same.
+func same(a, b interface{}) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if a == nil {
+		return true
+	}
+	return reflect.ValueOf(a).Comparable() && a == b
+}
+
+// Filter returns a new chain made of the elements of v's chain for which pred
+// returns true, in the same order. If there are none, Filter returns nil. An
+// element that branches is not passed to pred, but is replaced by the result
+// of calling Join with its filtered children, and dropped if none remain.
+//
+// The original chain is not modified, and the kept elements are chained as
+// described for Map, so the new chain does not reference the dropped
+// elements.
+func Filter(v interface{}, pred func(interface{}) bool) interface{} {
+	return rechain(v, func(e interface{}) (interface{}, bool) {
+		return e, pred(e)
+	})
+}
+
+// Partition splits the elements of v's chain into those for which pred
+// returns true and the rest, each from outermost to innermost, in a single
+// walk of the chain.
+func Partition(v interface{}, pred func(interface{}) bool) (matched, rest []interface{}) {
+	walk(v, func(e interface{}) bool {
+		if pred(e) {
+			matched = append(matched, e)
+		} else {
+			rest = append(rest, e)
+		}
+		return true
+	})
+	return matched, rest
+}
+
+// Dedup returns a new chain made of the elements of v's chain with duplicates
+// removed, in the same order. An element is a duplicate if an element kept
+// from further out in the chain matches it, using the rules described by Is,
+// so the outermost of each set of duplicates is kept. Branches are handled
+// as described for Filter, and elements are compared across branches in the
+// order described by Walk.
+//
+// The original chain is not modified, and the kept elements are chained as
+// described for Map.
+func Dedup(v interface{}) interface{} {
+	var kept []interface{}
+	return rechain(v, func(e interface{}) (interface{}, bool) {
+		for _, k := range kept {
+			if match(k, e) {
+				return nil, false
+			}
+		}
+		kept = append(kept, e)
+		return e, true
+	})
+}
+
+// Prune returns a copy of v's chain with every branch whose first element
+// satisfies pred removed, where a branch is an element together with
+// everything it wraps. If v's own element satisfies pred, Prune returns nil.
+// The original chain is not modified.
+//
+// In a chain that does not branch, this removes the first element satisfying
+// pred and every element inside it. An element that branches, as described
+// by Walk, is replaced by the result of calling Join with its pruned
+// children, and is removed if none remain.
+//
+// The kept elements are chained as described for Map, so the new chain does
+// not reference the removed branches.
+func Prune(v interface{}, pred func(interface{}) bool) interface{} {
+	var kept []interface{}
+	for {
+		e := elem(v)
+		if pred(e) {
+			break
+		}
+
+		if bs := branches(e); bs != nil {
+			var children []interface{}
+			for _, c := range bs {
+				children = append(children, Prune(c, pred))
+			}
+			if j := Join(children...); j != nil {
+				kept = append(kept, j)
+			}
+		} else {
+			kept = append(kept, e)
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			break
+		}
+	}
+
+	if len(kept) == 0 {
+		return nil
+	}
+	return relink(reversed(kept))
+}
+
+// detacher is implemented by the links provided by this package, so that they
+// can be rechained without modifying the original.
+type detacher interface {
+	// detached returns a copy of the link that wraps nothing.
+	detached() iface.Wrap
+}
+
+// relink chains together vals, which must not be empty, with vals[0] as the
+// innermost value, like Build, but without calling the Wrap method of any of
+// vals. Links provided by this package, such as *Link and *Result, are copied
+// and the copies are chained instead, so the copies do not reference
+// anything the originals wrapped. Other values are held in new links, which
+// are never unwrapped past the value they hold, so wrappers defined outside
+// this package are not modified, and what they wrapped is not part of the
+// new chain.
+func relink(vals []interface{}) interface{} {
+	return relinkFresh(vals, nil)
+}
+
+// relinkFresh is like relink, except that the values of vals for which fresh
+// is true are chained by Build's rules, calling their Wrap method.
+func relinkFresh(vals []interface{}, fresh []bool) interface{} {
+	var src interface{}
+	for i, v := range vals {
+		if fresh != nil && fresh[i] {
+			if w, ok := v.(iface.Wrap); i == 0 || ok && w.Wrap(src) {
+				src = v
+				continue
+			}
+		} else if d, ok := v.(detacher); ok {
+			w := d.detached()
+			if i > 0 && !w.Wrap(src) {
+				panic("detached link should always wrap")
+			}
+			src = w
+			continue
+		}
+
+		if _, ok := v.(iface.Unwrap); i == 0 && !ok {
+			src = v
+			continue
+		}
+
+		link := &buildLink{}
+		link.Link.Set(v)
+		if i > 0 {
+			link.Wrap(src)
+		}
+		src = link
+	}
+	return src
+}
+
+// rechain returns a new chain made of the results of calling fn on the
+// elements of v's chain that fn keeps, chained by relink. Elements that
+// branch are not passed to fn, but replaced by the result of calling Join
+// with their rechained children, and dropped if none remain. If no elements are kept, rechain returns nil.
+func rechain(v interface{}, fn func(interface{}) (interface{}, bool)) interface{} {
+	var kept []interface{}
+	for {
+		e := elem(v)
+		if bs := branches(e); bs != nil {
+			var children []interface{}
+			for _, c := range bs {
+				children = append(children, rechain(c, fn))
+			}
+			if j := Join(children...); j != nil {
+				kept = append(kept, j)
+			}
+		} else if r, keep := fn(e); keep {
+			kept = append(kept, r)
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			break
+		}
+	}
+
+	if len(kept) == 0 {
+		return nil
+	}
+	return relink(reversed(kept))
+}
@@ -0,0 +1,263 @@
+package chain_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestMap(t *testing.T) {
+	orig := chain.Build("a", "b", "c")
+	upper := func(v interface{}) interface{} {
+		return strings.ToUpper(v.(string))
+	}
+
+	mapped := chain.Map(orig, upper)
+	assert.Equals(t, []interface{}{"C", "B", "A"}, chain.Slice(mapped))
+	assert.Equals(t, []interface{}{"c", "b", "a"}, chain.Slice(orig))
+	assert.True(t, chain.Is(mapped, "A"))
+
+	assert.Equals(t, "X", chain.Map("x", upper))
+
+	tree := chain.Build("z", chain.Join(chain.Build("a2", "a1"), "b"))
+	mapped = chain.Map(tree, upper)
+	assert.Equals(t, []interface{}{"A1", "A2", "B", "Z"}, chain.Slice(mapped)[1:])
+}
+
+func TestMapLinks(t *testing.T) {
+	r1, r2 := chain.Ok(1), chain.Ok(2)
+	ch := chain.Build(r1, r2)
+	mapped := chain.Map(ch, func(v interface{}) interface{} {
+		if v == r2 {
+			return "x"
+		}
+		return v
+	})
+	assert.Equals(t, []interface{}{r2, r1}, chain.Slice(ch))
+	assert.Equals(t, 2, chain.Len(mapped))
+	assert.Equals(t, "x", chain.Slice(mapped)[0])
+	assert.True(t, chain.Is(mapped, 1))
+
+	l := (&chain.Link{}).Set("b").SetID("id")
+	ch = chain.Build("a", l, "c")
+	mapped = chain.Map(ch, func(v interface{}) interface{} { return v })
+	assert.Equals(t, []interface{}{"c", l, "a"}, chain.Slice(ch))
+	assert.Equals(t, 3, chain.Len(mapped))
+	assert.True(t, chain.Is(mapped, "b"))
+
+	var got *chain.Link
+	assert.True(t, chain.As(mapped, &got))
+	assert.True(t, got != l)
+	id, _ := got.ID()
+	assert.Equals(t, "id", id)
+}
+
+func TestMapWrappers(t *testing.T) {
+	w := &picky{accept: true}
+	orig := chain.Build("a", w)
+	mapped := chain.Map(orig, func(v interface{}) interface{} {
+		if v == "a" {
+			return "x"
+		}
+		return v
+	})
+	assert.Equals(t, 2, chain.Len(mapped))
+	assert.True(t, chain.Slice(mapped)[0] == w)
+	assert.True(t, chain.Is(mapped, "x"))
+	assert.False(t, chain.Is(mapped, "a"))
+	assert.True(t, chain.Is(orig, "a"))
+}
+
+func TestRebuild(t *testing.T) {
+	upper := func(v interface{}) interface{} {
+		if s, ok := v.(string); ok {
+			return strings.ToUpper(s)
+		}
+		return v
+	}
+
+	orig := chain.Build("a", 1, "c")
+	rebuilt := chain.Rebuild(orig, upper)
+	assert.Equals(t, []interface{}{"C", 1, "A"}, chain.Slice(rebuilt))
+	assert.Equals(t, []interface{}{"c", 1, "a"}, chain.Slice(orig))
+
+	j := chain.Join("x", "y")
+	rebuilt = chain.Rebuild(chain.Build("a", j), upper)
+	assert.Equals(t, []interface{}{"A", j}, chain.Unbuild(rebuilt))
+
+	r1, r2 := chain.Ok(1), chain.Ok(2)
+	orig = chain.Build(r1, r2)
+	rebuilt = chain.Rebuild(orig, func(v interface{}) interface{} {
+		if v == r1 {
+			return "x"
+		}
+		return v
+	})
+	assert.Equals(t, []interface{}{r2, r1}, chain.Slice(orig))
+	assert.Equals(t, "x", chain.Root(rebuilt))
+	assert.Equals(t, 2, chain.Len(rebuilt))
+}
+
+func TestRebuildWrap(t *testing.T) {
+	old := &picky{accept: true}
+	orig := chain.Build("a", old)
+
+	var migrated *picky
+	rebuilt := chain.Rebuild(orig, func(v interface{}) interface{} {
+		if _, ok := v.(*picky); ok {
+			migrated = &picky{accept: true}
+			return migrated
+		}
+		return v
+	})
+	assert.True(t, rebuilt == migrated)
+	assert.Equals(t, 2, chain.Len(rebuilt))
+	assert.True(t, chain.Is(rebuilt, "a"))
+	inner, _ := chain.Unwrap(orig)
+	assert.Equals(t, "a", inner)
+
+	rebuilt = chain.Rebuild(orig, func(v interface{}) interface{} {
+		if v == "a" {
+			return "b"
+		}
+		return v
+	})
+	assert.True(t, chain.Slice(rebuilt)[0] == old)
+	assert.True(t, chain.Is(rebuilt, "b"))
+	assert.False(t, chain.Is(rebuilt, "a"))
+	assert.True(t, chain.Is(orig, "a"))
+}
+
+func TestFilter(t *testing.T) {
+	isString := func(v interface{}) bool {
+		_, ok := v.(string)
+		return ok
+	}
+
+	orig := chain.Build("a", 1, "b", 2)
+	filtered := chain.Filter(orig, isString)
+	assert.Equals(t, []interface{}{"b", "a"}, chain.Slice(filtered))
+	assert.False(t, chain.Is(filtered, 1))
+	assert.Equals(t, []interface{}{2, "b", 1, "a"}, chain.Slice(orig))
+
+	assert.Equals(t, nil, chain.Filter(chain.Build(1, 2), isString))
+
+	tree := chain.Build("z", chain.Join(chain.Build(1, "a"), 2))
+	filtered = chain.Filter(tree, isString)
+	assert.Equals(t, []interface{}{"a", "z"}, chain.Slice(filtered)[1:])
+	assert.False(t, chain.Is(filtered, 1))
+
+	errA, errB := errors.New("a"), errors.New("b")
+	filtered = chain.Filter(chain.Build(errors.Join(errA, errB), "x"), func(v interface{}) bool {
+		return v != errA
+	})
+	assert.Equals(t, 3, len(chain.Slice(filtered)))
+	assert.False(t, chain.Is(filtered, errA))
+	assert.True(t, chain.Is(filtered, errB))
+}
+
+func TestFilterLinks(t *testing.T) {
+	isLink := func(v interface{}) bool {
+		_, ok := v.(*chain.Link)
+		return ok
+	}
+
+	l := (&chain.Link{}).Set("b")
+	ch := chain.Build("x", l, "y")
+	filtered := chain.Filter(ch, isLink)
+	assert.Equals(t, 1, chain.Len(filtered))
+	assert.True(t, chain.Is(filtered, "b"))
+	assert.False(t, chain.Is(filtered, "x"))
+	assert.Equals(t, []interface{}{"y", l, "x"}, chain.Slice(ch))
+}
+
+func TestFilterWrappers(t *testing.T) {
+	notSecret := func(v interface{}) bool { return v != "secret" }
+
+	w := &picky{accept: true}
+	filtered := chain.Filter(chain.Build("secret", w), notSecret)
+	assert.Equals(t, 1, chain.Len(filtered))
+	assert.True(t, chain.Slice(filtered)[0] == w)
+	assert.False(t, chain.Is(filtered, "secret"))
+	assert.True(t, chain.Is(w, "secret"))
+}
+
+func TestPartition(t *testing.T) {
+	isString := func(v interface{}) bool {
+		_, ok := v.(string)
+		return ok
+	}
+
+	matched, rest := chain.Partition(chain.Build("a", 1, "b", 2), isString)
+	assert.Equals(t, []interface{}{"b", "a"}, matched)
+	assert.Equals(t, []interface{}{2, 1}, rest)
+
+	matched, rest = chain.Partition("a", isString)
+	assert.Equals(t, []interface{}{"a"}, matched)
+	assert.Equals(t, []interface{}(nil), rest)
+}
+
+func TestDedup(t *testing.T) {
+	orig := chain.Build("a", "b", "a", "c", "b")
+	assert.Equals(t, []interface{}{"b", "c", "a"}, chain.Slice(chain.Dedup(orig)))
+	assert.Equals(t, 5, chain.Len(orig))
+
+	m := &isMatcher{to: "x"}
+	assert.Equals(t, []interface{}{m, "y"}, chain.Slice(chain.Dedup(chain.Build("y", "x", m))))
+
+	assert.Equals(t, "a", chain.Dedup("a"))
+
+	r := chain.Ok(1)
+	orig = chain.Build("a", r, "a")
+	deduped := chain.Dedup(orig)
+	assert.Equals(t, 2, chain.Len(deduped))
+	assert.Equals(t, 1, chain.Count(deduped, "a"))
+	assert.Equals(t, []interface{}{"a", r, "a"}, chain.Slice(orig))
+
+	w := &picky{accept: true}
+	deduped = chain.Dedup(chain.Build("a", w, "a"))
+	assert.Equals(t, 2, chain.Len(deduped))
+	assert.Equals(t, 1, chain.Count(deduped, "a"))
+	assert.True(t, chain.Slice(deduped)[1] == w)
+}
+
+func TestPrune(t *testing.T) {
+	isDebug := func(v interface{}) bool {
+		s, ok := v.(string)
+		return ok && strings.HasPrefix(s, "debug")
+	}
+
+	orig := chain.Build("a", "debug", "b", "c")
+	assert.Equals(t, []interface{}{"c", "b"}, chain.Slice(chain.Prune(orig, isDebug)))
+	assert.Equals(t, 4, chain.Len(orig))
+
+	tree := chain.Build("z", chain.Join(chain.Build("a2", "a1"), chain.Build("x", "debug-b"), "c"), "top")
+	pruned := chain.Prune(tree, isDebug)
+	assert.False(t, chain.Is(pruned, "debug-b"))
+	assert.False(t, chain.Is(pruned, "x"))
+	assert.Equals(t, "top", chain.Slice(pruned)[0])
+	assert.Equals(t, []interface{}{"a1", "a2", "c", "z"}, chain.Slice(pruned)[2:])
+	assert.True(t, chain.Is(tree, "debug-b"))
+
+	onlyDebug := chain.Build("z", chain.Join("debug-1", "debug-2"))
+	assert.Equals(t, "z", chain.Prune(onlyDebug, isDebug))
+
+	assert.Equals(t, nil, chain.Prune(chain.Build("a", "debug"), isDebug))
+
+	lb := (&chain.Link{}).Set("b")
+	orig = chain.Build("debug", lb, "c")
+	pruned = chain.Prune(orig, isDebug)
+	assert.False(t, chain.Is(pruned, "debug"))
+	assert.True(t, chain.Is(pruned, "b"))
+	assert.Equals(t, 2, chain.Len(pruned))
+	assert.Equals(t, []interface{}{"c", lb, "debug"}, chain.Slice(orig))
+
+	w := &picky{accept: true}
+	pruned = chain.Prune(chain.Build("debug", w), isDebug)
+	assert.Equals(t, 1, chain.Len(pruned))
+	assert.True(t, chain.Slice(pruned)[0] == w)
+	assert.False(t, chain.Is(pruned, "debug"))
+}
@@ -0,0 +1,118 @@
+package chain
+
+import (
+	"reflect"
+
+	"github.com/rbranson/chain/iface"
+)
+
+// TypedLink is a chainable wrapper like Link, but for a held value of a
+// specific type T, so that the held value can be retrieved without a type
+// assertion. It implements the same interfaces as Link, so it interoperates
+// with the rest of the package.
+type TypedLink[T any] struct {
+	h Holder
+	v T
+}
+
+// Set sets the TypedLink's held value to v
+func (l *TypedLink[T]) Set(v T) *TypedLink[T] {
+	l.v = v
+	return l
+}
+
+// Value returns the TypedLink's held value
+func (l *TypedLink[T]) Value() T {
+	return l.v
+}
+
+// Unwrap unwraps the wrapped value
+func (l *TypedLink[T]) Unwrap() (interface{}, bool) {
+	return l.h.Get()
+}
+
+// Wrap sets the TypedLink's wrapped value
+func (l *TypedLink[T]) Wrap(v interface{}) bool {
+	l.h.Set(v)
+	return true
+}
+
+// Is returns true if the target is a T that equals the held value, as
+// determined by reflect.DeepEqual, since T need not be comparable
+func (l *TypedLink[T]) Is(target interface{}) bool {
+	t, ok := target.(T)
+	return ok && reflect.DeepEqual(l.v, t)
+}
+
+// As returns chain.As(v, target) where v is the held value
+func (l *TypedLink[T]) As(target interface{}) bool {
+	return As(l.v, target)
+}
+
+func (l *TypedLink[T]) detached() iface.Wrap {
+	c := *l
+	c.h = Holder{}
+	return &c
+}
+
+// TypedHolder is like Holder, but holds a value of a specific type T, so
+// that it can be retrieved without a type assertion.
+type TypedHolder[T any] struct {
+	Value T
+	Ok    bool
+}
+
+// Set sets the TypedHolder's Value to v.
+func (h *TypedHolder[T]) Set(v T) {
+	h.Value = v
+	h.Ok = true
+}
+
+// Get returns the TypedHolder's Value and the "filled" assertion.
+func (h *TypedHolder[T]) Get() (T, bool) {
+	if !h.Ok {
+		var zero T
+		return zero, false
+	}
+	return h.Value, true
+}
+
+// HoldTyped builds a new TypedHolder and sets it to v
+func HoldTyped[T any](v T) TypedHolder[T] {
+	h := TypedHolder[T]{}
+	h.Set(v)
+	return h
+}
+
+// TypedChain iterates over the values of type T in a chain, skipping any
+// values of other types.
+type TypedChain[T any] struct {
+	next Holder
+}
+
+// NewTypedChain returns a TypedChain over the values of type T in v's chain.
+func NewTypedChain[T any](v interface{}) *TypedChain[T] {
+	return &TypedChain[T]{next: Hold(v)}
+}
+
+// Next returns the next value of type T in the chain, from outermost to
+// innermost, or false once there are none left. The chain is unwrapped
+// lazily, as values are requested.
+func (c *TypedChain[T]) Next() (T, bool) {
+	for {
+		v, ok := c.next.Get()
+		if !ok {
+			var zero T
+			return zero, false
+		}
+
+		c.next = Holder{}
+		if next, ok := Unwrap(v); ok {
+			c.next.Set(next)
+		}
+
+		if t, ok := elem(v).(T); ok {
+			return t, true
+		}
+	}
+}
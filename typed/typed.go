@@ -0,0 +1,41 @@
+// Package typed provides a generics-first API for manipulating chains of
+// values, built on the reflection-based API of package chain.
+//
+// Functions in this package use type parameters in place of pointer targets
+// and reflection, so misuse is caught at compile time rather than by a panic,
+// and matching avoids the cost of reflection. Chains are ordinary values and
+// can be passed freely between this package and package chain.
+package typed
+
+import "github.com/rbranson/chain"
+
+// Unwrap returns the result of calling the Unwrap method on v, if v's type
+// implements iface.Unwrap. Otherwise, Unwrap returns nil and false.
+func Unwrap(v interface{}) (interface{}, bool) {
+	return chain.Unwrap(v)
+}
+
+// Is reports whether any value in v's chain matches target. Values of type T
+// are compared with target using ==, and other values can match through an
+// Is method. See chain.IsOf.
+func Is[T comparable](v interface{}, target T) bool {
+	return chain.IsOf(v, target)
+}
+
+// As returns the first value in v's chain that is a T, or that has an As
+// method which fills a T. See chain.AsFor.
+func As[T any](v interface{}) (T, bool) {
+	return chain.AsFor[T](v)
+}
+
+// Build chains together first and rest using the rules described by
+// chain.Build, with first as the innermost value, returning the outermost.
+// Requiring first means a chain can never be built from zero values.
+func Build[T any](first T, rest ...T) interface{} {
+	vals := make([]interface{}, 0, len(rest)+1)
+	vals = append(vals, first)
+	for _, v := range rest {
+		vals = append(vals, v)
+	}
+	return chain.Build(vals...)
+}
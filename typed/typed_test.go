@@ -0,0 +1,32 @@
+package typed_test
+
+import (
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+	"github.com/rbranson/chain/typed"
+)
+
+func TestTyped(t *testing.T) {
+	ch := typed.Build("a", "b", "c")
+	assert.True(t, typed.Is(ch, "a"))
+	assert.True(t, typed.Is(ch, "c"))
+	assert.False(t, typed.Is(ch, "d"))
+	assert.True(t, chain.Is(ch, "b"))
+
+	s, ok := typed.As[string](ch)
+	assert.True(t, ok)
+	assert.Equals(t, "c", s)
+
+	_, ok = typed.As[int](ch)
+	assert.False(t, ok)
+
+	inner, ok := typed.Unwrap(ch)
+	assert.True(t, ok)
+	s, ok = typed.As[string](inner)
+	assert.True(t, ok)
+	assert.Equals(t, "b", s)
+
+	assert.Equals(t, 1, typed.Build(1))
+}
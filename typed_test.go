@@ -0,0 +1,66 @@
+package chain_test
+
+import (
+	"testing"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/iface"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestTypedLink(t *testing.T) {
+	l := &chain.TypedLink[[]string]{}
+	assert.Implements(t, (*iface.Unwrap)(nil), l)
+	assert.Implements(t, (*iface.Wrap)(nil), l)
+	assert.Implements(t, (*iface.Is)(nil), l)
+	assert.Implements(t, (*iface.As)(nil), l)
+
+	l.Set([]string{"a", "b"})
+	assert.Equals(t, []string{"a", "b"}, l.Value())
+
+	ch := chain.Build("inner", l)
+	assert.True(t, chain.Is(ch, []string{"a", "b"}))
+	assert.True(t, chain.Is(ch, "inner"))
+	assert.False(t, chain.Is(ch, []string{"a"}))
+
+	var s []string
+	assert.True(t, chain.As(ch, &s))
+	assert.Equals(t, []string{"a", "b"}, s)
+
+	u, ok := chain.Unwrap(ch)
+	assert.True(t, ok)
+	assert.Equals(t, "inner", u)
+}
+
+func TestTypedHolder(t *testing.T) {
+	var h chain.TypedHolder[int]
+	v, ok := h.Get()
+	assert.False(t, ok)
+	assert.Equals(t, 0, v)
+
+	h.Set(0)
+	v, ok = h.Get()
+	assert.True(t, ok)
+	assert.Equals(t, 0, v)
+
+	h2 := chain.HoldTyped("a")
+	s, ok := h2.Get()
+	assert.True(t, ok)
+	assert.Equals(t, "a", s)
+}
+
+func TestTypedChain(t *testing.T) {
+	c := chain.NewTypedChain[string](chain.Build("a", 1, "b", 2.5, "c"))
+
+	var got []string
+	for s, ok := c.Next(); ok; s, ok = c.Next() {
+		got = append(got, s)
+	}
+	assert.Equals(t, []string{"c", "b", "a"}, got)
+
+	_, ok := c.Next()
+	assert.False(t, ok)
+
+	_, ok = chain.NewTypedChain[int]("a").Next()
+	assert.False(t, ok)
+}
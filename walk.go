@@ -0,0 +1,556 @@
+package chain
+
+import (
+	"context"
+	"iter"
+	"reflect"
+	"sync"
+
+	"github.com/rbranson/chain/iface"
+	"github.com/rbranson/chain/x"
+)
+
+// elem returns the value v represents as a chain element. Links synthesized
+// by Build and BuildLazy are represented by the value they hold, since they are an
+// implementation detail of Build rather than values the caller chained.
+func elem(v interface{}) interface{} {
+	switch l := v.(type) {
+	case *buildLink:
+		return l.v
+	case *lazyLink:
+		return l.v
+	}
+	return v
+}
+
+// walk calls fn with each element of v's chain, from outermost to innermost,
+// until fn returns false. The chains of the children of elements that branch,
+// as returned by branches, are walked in order, immediately after their
+// parent.
+func walk(v interface{}, fn func(interface{}) bool) {
+	walkTree(v, fn)
+}
+
+// walkTree implements walk, returning false if fn did.
+func walkTree(v interface{}, fn func(interface{}) bool) bool {
+	for {
+		e := elem(v)
+		if !fn(e) {
+			return false
+		}
+
+		for _, c := range branches(e) {
+			if !walkTree(c, fn) {
+				return false
+			}
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return true
+		}
+	}
+}
+
+// walkDepth is like walk, but also passes fn the depth of each element: the
+// number of values wrapped along the path from v to it, so v's element has a
+// depth of 0, and the children of an element at depth d have a depth of d+1,
+// as does the value it wraps.
+func walkDepth(v interface{}, fn func(depth int, e interface{}) bool) {
+	walkDepthFrom(v, 0, fn)
+}
+
+// walkDepthFrom implements walkDepth for a chain whose first element is at
+// the given depth, returning false if fn did.
+func walkDepthFrom(v interface{}, depth int, fn func(depth int, e interface{}) bool) bool {
+	for ; ; depth++ {
+		e := elem(v)
+		if !fn(depth, e) {
+			return false
+		}
+
+		for _, c := range branches(e) {
+			if !walkDepthFrom(c, depth+1, fn) {
+				return false
+			}
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return true
+		}
+	}
+}
+
+// collect returns the elements of v's chain, from outermost to innermost.
+func collect(v interface{}) []interface{} {
+	var elems []interface{}
+	walk(v, func(e interface{}) bool {
+		elems = append(elems, e)
+		return true
+	})
+	return elems
+}
+
+// Order is the order in which the elements of a branching chain are visited.
+// For a chain that does not branch, every Order visits the elements from
+// outermost to innermost.
+type Order int
+
+const (
+	// DepthFirst visits each element before the chains of its children, and
+	// visits the whole chain of one child before moving on to the next. It is
+	// the order used by Walk, Is and As.
+	DepthFirst Order = iota
+
+	// BreadthFirst visits elements level by level, in order of their distance
+	// from v, so that elements near the outside of every branch are visited
+	// before elements deep inside any of them. At each level, the children of
+	// an element are visited before the element it wraps, if any.
+	BreadthFirst
+)
+
+// walkOrder is like walk, but visits the elements in the given order.
+func walkOrder(v interface{}, order Order, fn func(interface{}) bool) {
+	switch order {
+	case DepthFirst:
+		walk(v, fn)
+	case BreadthFirst:
+		queue := []interface{}{v}
+		for len(queue) > 0 {
+			v = queue[0]
+			queue = queue[1:]
+
+			e := elem(v)
+			if !fn(e) {
+				return
+			}
+			queue = append(queue, branches(e)...)
+			if next, ok := Unwrap(v); ok {
+				queue = append(queue, next)
+			}
+		}
+	default:
+		panic("chain: unknown order")
+	}
+}
+
+// Walk calls fn for each element of v's chain, from outermost to innermost,
+// until fn returns false.
+//
+// The elements of a chain are v itself followed by the values obtained by
+// repeatedly calling Unwrap, except that the links Build synthesizes are
+// represented by the values they hold.
+//
+// A chain may branch: if an element implements iface.UnwrapMulti, such as
+// those returned by Join, or Unwrap() []error, such as those returned by
+// errors.Join, the chain of each of its children is walked in turn, depth
+// first, immediately after the element itself and before the rest of the
+// chain. The same order
+// applies to every function that walks a chain, such as Slice and Find.
+func Walk(v interface{}, fn func(interface{}) bool) {
+	walk(v, fn)
+}
+
+// WalkOrder is like Walk, but visits the elements of a branching chain in the
+// given order.
+//
+// WalkOrder panics if order is not DepthFirst or BreadthFirst.
+func WalkOrder(v interface{}, order Order, fn func(interface{}) bool) {
+	walkOrder(v, order, fn)
+}
+
+// WalkParallel calls fn for each element of v's chain, like Walk, but walks
+// the branches of a branching chain concurrently, using at most workers
+// goroutines, including the calling one. It returns once fn has returned for
+// every element. This is intended for chains whose elements need expensive
+// processing; for other chains, the overhead outweighs the benefit.
+//
+// fn may be called concurrently and so must be safe for concurrent use. The
+// elements of each unbranched run of the chain are visited in order, from
+// outermost to innermost, but there is no ordering between branches.
+//
+// WalkParallel panics if workers is less than 1.
+func WalkParallel(v interface{}, workers int, fn func(interface{})) {
+	if workers < 1 {
+		panic("chain: workers must be at least 1")
+	}
+
+	var wg sync.WaitGroup
+	walkParallel(v, fn, make(chan struct{}, workers-1), &wg)
+	wg.Wait()
+}
+
+// walkParallel implements WalkParallel, starting a goroutine for a branch if
+// a slot in sem is free, and otherwise walking it in the current one.
+func walkParallel(v interface{}, fn func(interface{}), sem chan struct{}, wg *sync.WaitGroup) {
+	for {
+		e := elem(v)
+		fn(e)
+
+		for _, c := range branches(e) {
+			select {
+			case sem <- struct{}{}:
+				wg.Add(1)
+				go func(c interface{}) {
+					defer wg.Done()
+					defer func() { <-sem }()
+					walkParallel(c, fn, sem, wg)
+				}(c)
+			default:
+				walkParallel(c, fn, sem, wg)
+			}
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return
+		}
+	}
+}
+
+// Slice returns the elements of v's chain, from outermost to innermost, as
+// described by Walk.
+func Slice(v interface{}) []interface{} {
+	return collect(v)
+}
+
+// FlattenTree returns the elements of v's chain, including those in every
+// branch, in the given order. Unlike Slice, the order is explicit, so the
+// result is stable even if the default order used by Walk were to change.
+//
+// FlattenTree panics if order is not DepthFirst or BreadthFirst.
+func FlattenTree(v interface{}, order Order) []interface{} {
+	var elems []interface{}
+	walkOrder(v, order, func(e interface{}) bool {
+		elems = append(elems, e)
+		return true
+	})
+	return elems
+}
+
+// Root returns the innermost element of v's chain: the last value, obtained by
+// repeatedly calling Unwrap, that cannot be unwrapped further. If v cannot be
+// unwrapped, Root returns v itself. If the chain branches, Root returns the
+// last element walked, as described by Walk.
+func Root(v interface{}) interface{} {
+	var root interface{}
+	walk(v, func(e interface{}) bool {
+		root = e
+		return true
+	})
+	return root
+}
+
+// Nth returns the element at position n of v's chain, as described by Walk,
+// where 0 is v itself. If n is negative or the chain has n or fewer elements,
+// Nth returns nil and false.
+func Nth(v interface{}, n int) (interface{}, bool) {
+	if n < 0 {
+		return nil, false
+	}
+
+	var (
+		found interface{}
+		ok    bool
+		i     int
+	)
+	walk(v, func(e interface{}) bool {
+		if i == n {
+			found, ok = e, true
+			return false
+		}
+		i++
+		return true
+	})
+	return found, ok
+}
+
+// Types returns the concrete type of each element of v's chain, from
+// outermost to innermost. The type of a nil element is nil.
+func Types(v interface{}) []reflect.Type {
+	var types []reflect.Type
+	walk(v, func(e interface{}) bool {
+		types = append(types, reflect.TypeOf(e))
+		return true
+	})
+	return types
+}
+
+// Find returns the first element of v's chain, from outermost to innermost,
+// for which pred returns true. If there is none, Find returns nil and false.
+func Find(v interface{}, pred func(interface{}) bool) (interface{}, bool) {
+	var (
+		found interface{}
+		ok    bool
+	)
+	walk(v, func(e interface{}) bool {
+		if pred(e) {
+			found, ok = e, true
+			return false
+		}
+		return true
+	})
+	return found, ok
+}
+
+// FindAll returns every element of v's chain for which pred returns true,
+// from outermost to innermost. If there are none, FindAll returns nil.
+func FindAll(v interface{}, pred func(interface{}) bool) []interface{} {
+	var found []interface{}
+	walk(v, func(e interface{}) bool {
+		if pred(e) {
+			found = append(found, e)
+		}
+		return true
+	})
+	return found
+}
+
+// Reduce folds the elements of v's chain into a single value. It calls fn
+// with acc and the outermost element, then with the result of that call and
+// the next element, and so on, returning the result of the last call.
+func Reduce(v interface{}, acc interface{}, fn func(acc, elem interface{}) interface{}) interface{} {
+	walk(v, func(e interface{}) bool {
+		acc = fn(acc, e)
+		return true
+	})
+	return acc
+}
+
+// FindOrder is like Find, but searches the elements of a branching chain in
+// the given order, so it returns the first element satisfying pred in that
+// order.
+//
+// FindOrder panics if order is not DepthFirst or BreadthFirst.
+func FindOrder(v interface{}, order Order, pred func(interface{}) bool) (interface{}, bool) {
+	var (
+		found interface{}
+		ok    bool
+	)
+	walkOrder(v, order, func(e interface{}) bool {
+		if pred(e) {
+			found, ok = e, true
+			return false
+		}
+		return true
+	})
+	return found, ok
+}
+
+// WalkDeep calls fn for each element of v's chain, from outermost to
+// innermost, until fn returns false. Unlike Walk, it also descends
+// into nested chains: when a value held by a link (a *Link, or a link
+// synthesized by Build or BuildLazy) is itself a chain, the elements of that nested chain
+// are visited immediately after the element that contains it, before the
+// walk continues with the rest of the outer chain.
+//
+// A value reached again while its own chain, or a chain nested inside it, is
+// being walked, compared by identity, is not walked again, so cyclic chains
+// terminate. Values that merely appear more than once are visited each time.
+func WalkDeep(v interface{}, fn func(interface{}) bool) {
+	walkDeep(v, fn, make(map[identity]struct{}))
+}
+
+// walkDeep implements WalkDeep, where path holds the identities of the values
+// being walked by the calls that led to this one.
+func walkDeep(v interface{}, fn func(interface{}) bool, path map[identity]struct{}) bool {
+	var entered []identity
+	defer func() {
+		for _, id := range entered {
+			delete(path, id)
+		}
+	}()
+
+	for {
+		if id, ok := identify(v); ok {
+			if _, ok := path[id]; ok {
+				return true
+			}
+			path[id] = struct{}{}
+			entered = append(entered, id)
+		}
+
+		switch l := v.(type) {
+		case *buildLink, *lazyLink:
+			if !walkDeep(elem(l), fn, path) {
+				return false
+			}
+		case *Link:
+			if !fn(l) {
+				return false
+			}
+			if _, ok := l.v.(iface.Unwrap); ok && !walkDeep(l.v, fn, path) {
+				return false
+			}
+		default:
+			if !fn(v) {
+				return false
+			}
+		}
+
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return true
+		}
+	}
+}
+
+// identity is a comparable key identifying a value by reference.
+type identity struct {
+	t reflect.Type
+	p uintptr
+}
+
+// identify returns the identity of v if it is a non-nil reference to
+// something, such as a pointer, map or channel.
+func identify(v interface{}) (identity, bool) {
+	rv, ok := x.ValueOf(v)
+	if !ok {
+		return identity{}, false
+	}
+
+	switch rv.Kind() {
+	case reflect.Chan, reflect.Map, reflect.Ptr, reflect.UnsafePointer:
+		return identity{t: rv.Type(), p: rv.Pointer()}, true
+	}
+	return identity{}, false
+}
+
+// WalkWithParent calls fn for each element of v's chain, from outermost to
+// innermost, until fn returns false. Along with each value, fn is passed the
+// node that wraps it, or nil for v itself.
+//
+// parent is the wrapping node as returned by Unwrap, not the value it
+// represents, so it may be a link synthesized by Build. This allows, for
+// example, retrieving the ID of the link that wrapped a value.
+func WalkWithParent(v interface{}, fn func(value, parent interface{}) bool) {
+	var parent interface{}
+	for {
+		if !fn(elem(v), parent) {
+			return
+		}
+
+		parent = v
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the elements of v's chain, from outermost to
+// innermost, for use with range:
+//
+//	for e := range chain.All(v) {
+//		...
+//	}
+func All(v interface{}) iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		walk(v, yield)
+	}
+}
+
+// AllOrder is like All, but yields the elements of a branching chain in the
+// given order.
+//
+// AllOrder panics if order is not DepthFirst or BreadthFirst.
+func AllOrder(v interface{}, order Order) iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		walkOrder(v, order, yield)
+	}
+}
+
+// Chan returns a channel that receives the elements of v's chain, from
+// outermost to innermost, and is closed after the innermost element. The
+// chain is walked by a new goroutine as elements are received.
+//
+// If ctx is done before every element has been received, the walk stops and
+// the channel is closed, so a consumer that stops receiving early should
+// cancel ctx to release the goroutine.
+func Chan(ctx context.Context, v interface{}) <-chan interface{} {
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		walk(v, func(e interface{}) bool {
+			select {
+			case ch <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+// Backward returns an iterator over the elements of v's chain in reverse
+// order, from innermost to outermost, which is the natural order for "caused
+// by" style output. Since chains can only be unwrapped from the outside in,
+// the whole chain is walked before the first element is yielded.
+func Backward(v interface{}) iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		elems := collect(v)
+		for i := len(elems) - 1; i >= 0; i-- {
+			if !yield(elems[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of elements in v's chain: 1 for v itself, plus 1 for
+// each successful call to Unwrap.
+//
+// Len detects chains that loop back to a node they have already passed
+// through, comparing nodes by identity, and returns -1 for them rather than
+// counting forever. Detection uses Brent's algorithm, so it needs no memory
+// beyond a single node. Only nodes with an identity, such as pointers, can be
+// marked, so when a mark falls due on a node without one, it is placed on the
+// next node that has one instead. A cycle made only of nodes without an
+// identity is not detected.
+func Len(v interface{}) int {
+	var (
+		mark     identity
+		marked   bool
+		n        = 1
+		power    = 1
+		distance = 0
+	)
+	for {
+		var ok bool
+		v, ok = Unwrap(v)
+		if !ok {
+			return n
+		}
+		n++
+
+		id, ok := identify(v)
+		if ok && marked && id == mark {
+			return -1
+		}
+
+		distance++
+		if distance >= power && ok {
+			mark, marked = id, true
+			power *= 2
+			distance = 0
+		}
+	}
+}
+
+// Depth returns the number of times Unwrap can be called successfully on v's
+// chain, which is one less than its Len. Like Len, it returns -1 if the chain
+// is cyclic.
+func Depth(v interface{}) int {
+	n := Len(v)
+	if n < 0 {
+		return n
+	}
+	return n - 1
+}
@@ -0,0 +1,418 @@
+package chain_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rbranson/chain"
+	"github.com/rbranson/chain/internal/assert"
+)
+
+func TestWalkDeep(t *testing.T) {
+	var got []interface{}
+	visit := func(v interface{}) bool {
+		got = append(got, v)
+		return true
+	}
+
+	inner := &unwrappable{wrapped: chain.Hold("b")}
+	chain.WalkDeep(chain.Build("a", inner, "d"), visit)
+	assert.Equals(t, []interface{}{"d", inner, "b", "a"}, got)
+
+	got = nil
+	nested := (&chain.Link{}).Set(chain.Build("b", "c"))
+	nested.Wrap("a")
+	chain.WalkDeep(nested, visit)
+	assert.Equals(t, []interface{}{nested, "c", "b", "a"}, got)
+
+	// stops early
+	got = nil
+	chain.WalkDeep(chain.Build("a", inner, "d"), func(v interface{}) bool {
+		got = append(got, v)
+		return v != "b"
+	})
+	assert.Equals(t, []interface{}{"d", inner, "b"}, got)
+
+	// cycles terminate
+	got = nil
+	cyclic := &chain.Link{}
+	cyclic.Set(cyclic)
+	cyclic.Wrap(cyclic)
+	chain.WalkDeep(cyclic, visit)
+	assert.Equals(t, []interface{}{cyclic}, got)
+
+	// repeated values are visited each time
+	got = nil
+	y := 1
+	py := &y
+	chain.WalkDeep(chain.Build(py, "s", py), visit)
+	assert.Equals(t, []interface{}{py, "s", py}, got)
+
+	got = nil
+	shared := chain.Build("b", "c")
+	chain.WalkDeep(chain.Build((&chain.Link{}).Set(shared), (&chain.Link{}).Set(shared)), func(v interface{}) bool {
+		if _, ok := v.(*chain.Link); !ok {
+			got = append(got, v)
+		}
+		return true
+	})
+	assert.Equals(t, []interface{}{"c", "b", "c", "b"}, got)
+}
+
+func TestWalkWithParent(t *testing.T) {
+	w := &unwrappable{wrapped: chain.Hold("a")}
+	ch := chain.Build(w, "b")
+
+	var values, parents []interface{}
+	chain.WalkWithParent(ch, func(v, parent interface{}) bool {
+		values = append(values, v)
+		parents = append(parents, parent)
+		return true
+	})
+	assert.Equals(t, []interface{}{"b", w, "a"}, values)
+	assert.Equals(t, nil, parents[0])
+	assert.True(t, parents[1] == ch)
+	assert.True(t, parents[2] == w)
+
+	values = nil
+	chain.WalkWithParent(ch, func(v, parent interface{}) bool {
+		values = append(values, v)
+		return parent == nil
+	})
+	assert.Equals(t, []interface{}{"b", w}, values)
+}
+
+func TestAll(t *testing.T) {
+	var got []interface{}
+	for e := range chain.All(chain.Build("a", 1, "c")) {
+		got = append(got, e)
+	}
+	assert.Equals(t, []interface{}{"c", 1, "a"}, got)
+
+	got = nil
+	for e := range chain.All(chain.Build("a", 1, "c")) {
+		if e == 1 {
+			break
+		}
+		got = append(got, e)
+	}
+	assert.Equals(t, []interface{}{"c"}, got)
+}
+
+func TestChan(t *testing.T) {
+	var got []interface{}
+	for e := range chain.Chan(context.Background(), chain.Build("a", 1, "c")) {
+		got = append(got, e)
+	}
+	assert.Equals(t, []interface{}{"c", 1, "a"}, got)
+
+	cyclic := (&chain.Link{}).Set("x")
+	cyclic.Wrap(cyclic)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := chain.Chan(ctx, cyclic)
+	for i := 0; i < 3; i++ {
+		assert.Equals(t, cyclic, <-ch)
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+}
+
+func TestBackward(t *testing.T) {
+	var got []interface{}
+	for e := range chain.Backward(chain.Build("a", 1, "c")) {
+		got = append(got, e)
+	}
+	assert.Equals(t, []interface{}{"a", 1, "c"}, got)
+
+	got = nil
+	for e := range chain.Backward(chain.Build("a", 1, "c")) {
+		if e == 1 {
+			break
+		}
+		got = append(got, e)
+	}
+	assert.Equals(t, []interface{}{"a"}, got)
+}
+
+func TestWalk(t *testing.T) {
+	w := &unwrappable{wrapped: chain.Hold("a")}
+	ch := chain.Build(w, 1, "c")
+
+	var got []interface{}
+	chain.Walk(ch, func(v interface{}) bool {
+		got = append(got, v)
+		return true
+	})
+	assert.Equals(t, []interface{}{"c", 1, w, "a"}, got)
+
+	got = nil
+	chain.Walk(ch, func(v interface{}) bool {
+		got = append(got, v)
+		return v != 1
+	})
+	assert.Equals(t, []interface{}{"c", 1}, got)
+
+	got = nil
+	chain.Walk("x", func(v interface{}) bool {
+		got = append(got, v)
+		return true
+	})
+	assert.Equals(t, []interface{}{"x"}, got)
+}
+
+func TestWalkMulti(t *testing.T) {
+	m := &multi{children: []interface{}{chain.Build("a", "b"), "c"}}
+	v := chain.Build("z", m, "x")
+	assert.Equals(t, []interface{}{"x", m, "b", "a", "c", "z"}, chain.Slice(v))
+
+	var got []interface{}
+	chain.Walk(v, func(e interface{}) bool {
+		got = append(got, e)
+		return e != "a"
+	})
+	assert.Equals(t, []interface{}{"x", m, "b", "a"}, got)
+}
+
+func TestWalkErrors(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	joined := errors.Join(errA, errB)
+	v := chain.Build(joined, "x")
+
+	assert.Equals(t, []interface{}{"x", joined, errA, errB}, chain.Slice(v))
+	assert.Equals(t, 1, chain.Count(v, errA))
+	assert.Equals(t, 3, chain.Index(v, errB))
+	assert.Equals(t, []interface{}{"x", joined, errA, errB}, chain.FlattenTree(v, chain.BreadthFirst))
+	assert.True(t, chain.IsOf(v, errB))
+}
+
+func TestWalkOrder(t *testing.T) {
+	left := chain.Build("l2", "l1")
+	right := chain.Build("r2", "r1")
+	j := chain.Join(left, right)
+	v := chain.Build("z", j, "x")
+
+	var got []interface{}
+	chain.WalkOrder(v, chain.DepthFirst, func(e interface{}) bool {
+		got = append(got, e)
+		return true
+	})
+	assert.Equals(t, []interface{}{"x", j, "l1", "l2", "r1", "r2", "z"}, got)
+
+	got = nil
+	for e := range chain.AllOrder(v, chain.BreadthFirst) {
+		got = append(got, e)
+	}
+	assert.Equals(t, []interface{}{"x", j, "l1", "r1", "z", "l2", "r2"}, got)
+
+	isR := func(e interface{}) bool {
+		s, ok := e.(string)
+		return ok && s[0] == 'r'
+	}
+	w := chain.Join(chain.Build("r9", "l1"), "r1")
+	found, ok := chain.FindOrder(w, chain.DepthFirst, isR)
+	assert.True(t, ok)
+	assert.Equals(t, "r9", found)
+
+	found, ok = chain.FindOrder(w, chain.BreadthFirst, isR)
+	assert.True(t, ok)
+	assert.Equals(t, "r1", found)
+
+	assert.Panics(t, "chain: unknown order", func() {
+		chain.WalkOrder(v, chain.Order(99), func(interface{}) bool { return true })
+	})
+}
+
+func TestWalkParallel(t *testing.T) {
+	branches := make([]interface{}, 20)
+	for i := range branches {
+		branches[i] = chain.Build(i*2, i*2+1)
+	}
+	v := chain.Build("z", chain.Join(branches...))
+
+	for _, workers := range []int{1, 4} {
+		var (
+			mu      sync.Mutex
+			got     = make(map[interface{}]int)
+			active  int32
+			maxSeen int32
+		)
+		chain.WalkParallel(v, workers, func(e interface{}) {
+			n := atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			mu.Lock()
+			if n > maxSeen {
+				maxSeen = n
+			}
+			got[e]++
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+		})
+
+		assert.Equals(t, 42, len(got))
+		for i := 0; i < 40; i++ {
+			assert.Equals(t, 1, got[i])
+		}
+		assert.Equals(t, 1, got["z"])
+		assert.True(t, maxSeen <= int32(workers))
+	}
+
+	assert.Panics(t, "chain: workers must be at least 1", func() {
+		chain.WalkParallel("a", 0, func(interface{}) {})
+	})
+}
+
+func TestSlice(t *testing.T) {
+	assert.Equals(t, []interface{}{"c", 1, "a"}, chain.Slice(chain.Build("a", 1, "c")))
+	assert.Equals(t, []interface{}{"a"}, chain.Slice("a"))
+	assert.Equals(t, []interface{}{nil}, chain.Slice(nil))
+}
+
+func TestFlattenTree(t *testing.T) {
+	j := chain.Join(chain.Build("a2", "a1"), chain.Build("b2", "b1"))
+	v := chain.Build("z", j)
+
+	assert.Equals(t, []interface{}{j, "a1", "a2", "b1", "b2", "z"}, chain.FlattenTree(v, chain.DepthFirst))
+	assert.Equals(t, []interface{}{j, "a1", "b1", "z", "a2", "b2"}, chain.FlattenTree(v, chain.BreadthFirst))
+	assert.Equals(t, []interface{}{"a"}, chain.FlattenTree("a", chain.BreadthFirst))
+}
+
+func TestLen(t *testing.T) {
+	assert.Equals(t, 1, chain.Len("a"))
+	assert.Equals(t, 1, chain.Len(nil))
+	assert.Equals(t, 3, chain.Len(chain.Build("a", "b", "c")))
+	assert.Equals(t, 0, chain.Depth("a"))
+	assert.Equals(t, 2, chain.Depth(chain.Build("a", "b", "c")))
+
+	for _, n := range []int{1, 2, 3, 7, 100} {
+		links := make([]*chain.Link, n)
+		for i := range links {
+			links[i] = (&chain.Link{}).Set(i)
+		}
+		for i := range links {
+			links[i].Wrap(links[(i+1)%n])
+		}
+
+		assert.Equals(t, -1, chain.Len(links[0]))
+		assert.Equals(t, -1, chain.Len(chain.Build(links[0], "y")))
+		assert.Equals(t, -1, chain.Depth(links[0]))
+	}
+}
+
+type valueNode struct {
+	p *pointerNode
+}
+
+func (n valueNode) Unwrap() (interface{}, bool) {
+	return n.p, true
+}
+
+type pointerNode struct {
+	v valueNode
+}
+
+func (n *pointerNode) Unwrap() (interface{}, bool) {
+	return n.v, true
+}
+
+func TestLenMixedCycle(t *testing.T) {
+	p := &pointerNode{}
+	p.v = valueNode{p: p}
+	assert.Equals(t, -1, chain.Len(p))
+	assert.Equals(t, -1, chain.Len(p.v))
+
+	q := &pointerNode{}
+	p.v = valueNode{p: &pointerNode{v: valueNode{p: q}}}
+	q.v = valueNode{p: p}
+	assert.Equals(t, -1, chain.Len(p))
+}
+
+func TestRoot(t *testing.T) {
+	assert.Equals(t, "a", chain.Root("a"))
+	assert.Equals(t, "a", chain.Root(chain.Build("a", "b", "c")))
+	assert.Equals(t, "x", chain.Root(chain.Build(&unwrappable{wrapped: chain.Hold("x")}, "b")))
+	assert.Equals(t, nil, chain.Root(nil))
+}
+
+func TestNth(t *testing.T) {
+	v := chain.Build("a", "b", "c")
+	for i, want := range []string{"c", "b", "a"} {
+		got, ok := chain.Nth(v, i)
+		assert.True(t, ok)
+		assert.Equals(t, want, got)
+	}
+
+	_, ok := chain.Nth(v, 3)
+	assert.False(t, ok)
+	_, ok = chain.Nth(v, -1)
+	assert.False(t, ok)
+
+	got, ok := chain.Nth("a", 0)
+	assert.True(t, ok)
+	assert.Equals(t, "a", got)
+}
+
+func TestFind(t *testing.T) {
+	isInt := func(v interface{}) bool {
+		_, ok := v.(int)
+		return ok
+	}
+
+	got, ok := chain.Find(chain.Build(1, "a", 2, "b"), isInt)
+	assert.True(t, ok)
+	assert.Equals(t, 2, got)
+
+	got, ok = chain.Find(chain.Build("a", "b"), isInt)
+	assert.False(t, ok)
+	assert.Equals(t, nil, got)
+}
+
+func TestFindAll(t *testing.T) {
+	isInt := func(v interface{}) bool {
+		_, ok := v.(int)
+		return ok
+	}
+
+	assert.Equals(t, []interface{}{3, 2, 1}, chain.FindAll(chain.Build(1, "a", 2, 3, "b"), isInt))
+	assert.Equals(t, []interface{}(nil), chain.FindAll(chain.Build("a", "b"), isInt))
+}
+
+func TestReduce(t *testing.T) {
+	sum := chain.Reduce(chain.Build(1, 2, 3), 10, func(acc, e interface{}) interface{} {
+		return acc.(int) + e.(int)
+	})
+	assert.Equals(t, 16, sum)
+
+	joined := chain.Reduce(chain.Build("a", "b", "c"), "", func(acc, e interface{}) interface{} {
+		return acc.(string) + e.(string)
+	})
+	assert.Equals(t, "cba", joined)
+}
+
+func TestTypes(t *testing.T) {
+	got := chain.Types(chain.Build("a", 1, &unwrappable{wrapped: chain.Hold(nil)}))
+	want := []reflect.Type{
+		reflect.TypeOf(&unwrappable{}),
+		reflect.TypeOf(0),
+		reflect.TypeOf(""),
+	}
+	assert.Equals(t, want, got)
+
+	assert.Equals(t, []reflect.Type{nil}, chain.Types(nil))
+}